@@ -1,8 +1,11 @@
 package main
 
 import (
-	"io/ioutil"
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jmccann/drone-github-comment/plugin"
 
@@ -24,11 +27,32 @@ func main() {
 		// plugin args
 		//
 
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "config file with default settings and profiles",
+			Value:  plugin.DefaultConfigFile,
+			EnvVar: "PLUGIN_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "profile",
+			Usage:  "profile from the config file to use",
+			EnvVar: "PLUGIN_PROFILE",
+		},
 		cli.StringFlag{
 			Name:   "api-key",
 			Usage:  "api key to access github api",
 			EnvVar: "PLUGIN_API_KEY,GITHUB_RELEASE_API_KEY,GITHUB_TOKEN",
 		},
+		cli.StringFlag{
+			Name:   "api-key-file",
+			Usage:  "file containing the api key, e.g. a mounted secret",
+			EnvVar: "PLUGIN_API_KEY_FILE",
+		},
+		cli.StringFlag{
+			Name:   "credential-command",
+			Usage:  "shell command printing the api key",
+			EnvVar: "PLUGIN_CREDENTIAL_COMMAND",
+		},
 		cli.StringFlag{
 			Name:   "username",
 			Usage:  "basic auth username",
@@ -39,16 +63,45 @@ func main() {
 			Usage:  "basic auth password",
 			EnvVar: "PLUGIN_PASSWORD,GITHUB_PASSWORD,DRONE_NETRC_PASSWORD",
 		},
+		cli.StringFlag{
+			Name:   "netrc-file",
+			Usage:  "netrc file with credentials for the github host, defaults to ~/.netrc",
+			EnvVar: "PLUGIN_NETRC_FILE",
+		},
 		cli.StringFlag{
 			Name:   "base-url",
-			Value:  "https://api.github.com/",
-			Usage:  "api url, needs to be changed for ghe",
+			Usage:  "api url or ghe host, defaults to the host of the repo link",
 			EnvVar: "PLUGIN_BASE_URL,GITHUB_BASE_URL",
 		},
+		cli.StringFlag{
+			Name:   "ca-cert",
+			Usage:  "path to a PEM CA bundle trusted in addition to the system CAs",
+			EnvVar: "PLUGIN_CA_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-cert",
+			Usage:  "path to a PEM client certificate for mTLS",
+			EnvVar: "PLUGIN_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-key",
+			Usage:  "path to the PEM key of the client certificate",
+			EnvVar: "PLUGIN_CLIENT_KEY",
+		},
+		cli.BoolFlag{
+			Name:   "skip-verify",
+			Usage:  "skip TLS certificate verification (insecure)",
+			EnvVar: "PLUGIN_SKIP_VERIFY",
+		},
+		cli.StringFlag{
+			Name:   "proxy",
+			Usage:  "proxy URL for GitHub requests, honoring NO_PROXY",
+			EnvVar: "PLUGIN_PROXY",
+		},
 		cli.IntFlag{
 			Name:   "issue-num",
 			Usage:  "Issue #",
-			EnvVar: "PLUGIN_ISSUE_NUM,DRONE_PULL_REQUEST",
+			EnvVar: "PLUGIN_ISSUE_NUM",
 		},
 		cli.StringFlag{
 			Name: "key",
@@ -60,30 +113,290 @@ func main() {
 			Usage:  "comment message",
 			EnvVar: "PLUGIN_MESSAGE",
 		},
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:   "message-file",
-			Usage:  "comment message read from file",
+			Usage:  "comment message read from files or globs, - for stdin",
 			EnvVar: "PLUGIN_MESSAGE_FILE",
 		},
+		cli.StringFlag{
+			Name:   "on-empty",
+			Usage:  "fail, skip or delete the existing comment when the message is empty",
+			Value:  "fail",
+			EnvVar: "PLUGIN_ON_EMPTY",
+		},
+		cli.StringFlag{
+			Name:   "on-closed",
+			Usage:  "post, skip or fail when the PR/issue is closed",
+			Value:  "post",
+			EnvVar: "PLUGIN_ON_CLOSED",
+		},
+		cli.StringFlag{
+			Name:   "on-merged",
+			Usage:  "post, skip or fail when the PR is merged",
+			Value:  "post",
+			EnvVar: "PLUGIN_ON_MERGED",
+		},
+		cli.StringFlag{
+			Name:   "on-locked",
+			Usage:  "post, skip or fail when the conversation is locked",
+			Value:  "skip",
+			EnvVar: "PLUGIN_ON_LOCKED",
+		},
+		cli.StringFlag{
+			Name:   "on-redact",
+			Usage:  "mask secrets found in the comment, or fail",
+			Value:  "mask",
+			EnvVar: "PLUGIN_ON_REDACT",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-env",
+			Usage:  "environment variables whose values are redacted from the comment, defaults to DRONE_SECRET_*",
+			EnvVar: "PLUGIN_REDACT_ENV",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-patterns",
+			Usage:  "additional regular expressions redacted from the comment",
+			EnvVar: "PLUGIN_REDACT_PATTERNS",
+		},
+		cli.StringFlag{
+			Name:   "message-separator",
+			Usage:  "separator between message files, header and footer",
+			Value:  "\n",
+			EnvVar: "PLUGIN_MESSAGE_SEPARATOR",
+		},
+		cli.StringFlag{
+			Name:   "message-header-file",
+			Usage:  "file to prepend to the message",
+			EnvVar: "PLUGIN_MESSAGE_HEADER_FILE",
+		},
+		cli.StringFlag{
+			Name:   "message-footer-file",
+			Usage:  "file to append to the message",
+			EnvVar: "PLUGIN_MESSAGE_FOOTER_FILE",
+		},
+		cli.StringFlag{
+			Name:   "manifest",
+			Usage:  "YAML or JSON file listing multiple comments to post",
+			EnvVar: "PLUGIN_MANIFEST",
+		},
+		cli.StringFlag{
+			Name:   "manifest-policy",
+			Usage:  "fail_fast, fail_at_end or never fail on manifest entry errors",
+			Value:  "fail_at_end",
+			EnvVar: "PLUGIN_MANIFEST_POLICY",
+		},
 		cli.BoolFlag{
 			Name: "update",
 			Usage: "update an existing comment that matches the key",
 			EnvVar: "PLUGIN_UPDATE",
 		},
+		cli.StringFlag{
+			Name:   "resolve",
+			Usage:  "resolve the existing comment on success by update or minimize",
+			EnvVar: "PLUGIN_RESOLVE",
+		},
+		cli.StringFlag{
+			Name:   "resolve-message",
+			Usage:  "go template of the message for resolved comments",
+			EnvVar: "PLUGIN_RESOLVE_MESSAGE",
+		},
+		cli.StringSliceFlag{
+			Name:   "reactions",
+			Usage:  "reactions to add, optionally prefixed with a build status (failure:confused)",
+			EnvVar: "PLUGIN_REACTIONS",
+		},
+		cli.StringSliceFlag{
+			Name:   "reactions-remove",
+			Usage:  "reactions to remove, optionally prefixed with a build status (success:confused)",
+			EnvVar: "PLUGIN_REACTIONS_REMOVE",
+		},
+		cli.StringFlag{
+			Name:   "reaction-target",
+			Usage:  "react to the pr, the keyed comment or the trigger comment",
+			Value:  "pr",
+			EnvVar: "PLUGIN_REACTION_TARGET",
+		},
+		cli.Int64Flag{
+			Name:   "trigger-comment",
+			Usage:  "ID of the comment that triggered the build",
+			EnvVar: "PLUGIN_TRIGGER_COMMENT",
+		},
+		cli.StringSliceFlag{
+			Name:   "labels-add",
+			Usage:  "labels to add to the issue",
+			EnvVar: "PLUGIN_LABELS_ADD",
+		},
+		cli.StringSliceFlag{
+			Name:   "labels-remove",
+			Usage:  "labels to remove from the issue",
+			EnvVar: "PLUGIN_LABELS_REMOVE",
+		},
+		cli.StringFlag{
+			Name:   "label-color",
+			Usage:  "create missing labels with this hex color",
+			EnvVar: "PLUGIN_LABEL_COLOR",
+		},
+		cli.BoolFlag{
+			Name:   "codeowners",
+			Usage:  "load CODEOWNERS to mention owners of changed files in templates",
+			EnvVar: "PLUGIN_CODEOWNERS",
+		},
+		cli.BoolFlag{
+			Name:   "request-reviews",
+			Usage:  "request reviews from the owners of changed files",
+			EnvVar: "PLUGIN_REQUEST_REVIEWS",
+		},
+		cli.BoolFlag{
+			Name:   "preflight",
+			Usage:  "check the repository, issue and permissions before any writes",
+			EnvVar: "PLUGIN_PREFLIGHT",
+		},
+		cli.BoolFlag{
+			Name:   "print-env",
+			Usage:  "print the detected build environment and exit",
+			EnvVar: "PLUGIN_PRINT_ENV",
+		},
+		cli.StringFlag{
+			Name:   "fork-mode",
+			Usage:  "post, skip or write an artifact for PRs from forks",
+			Value:  "post",
+			EnvVar: "PLUGIN_FORK_MODE",
+		},
+		cli.StringFlag{
+			Name:   "artifact-file",
+			Usage:  "file to write pending comments of fork PRs to, a glob for post-pending",
+			Value:  plugin.DefaultArtifactFile,
+			EnvVar: "PLUGIN_ARTIFACT_FILE",
+		},
+		cli.DurationFlag{
+			Name:   "timeout",
+			Usage:  "overall time limit for GitHub requests, no limit if 0",
+			EnvVar: "PLUGIN_TIMEOUT",
+		},
+		cli.DurationFlag{
+			Name:   "request-timeout",
+			Usage:  "time limit for a single GitHub request",
+			Value:  30 * time.Second,
+			EnvVar: "PLUGIN_REQUEST_TIMEOUT",
+		},
+		cli.IntFlag{
+			Name:   "max-mentions",
+			Usage:  "maximum number of owners to mention or request reviews from, 0 for no limit",
+			Value:  10,
+			EnvVar: "PLUGIN_MAX_MENTIONS",
+		},
+		cli.BoolFlag{
+			Name:   "skip-comment",
+			Usage:  "do not post a comment, only run reactions, labels and status",
+			EnvVar: "PLUGIN_SKIP_COMMENT",
+		},
+		cli.StringFlag{
+			Name:   "search",
+			Usage:  "find the keyed comment from the oldest or newest comment (oldest, newest)",
+			Value:  "oldest",
+			EnvVar: "PLUGIN_SEARCH",
+		},
+		cli.StringFlag{
+			Name:   "since",
+			Usage:  "only search comments updated after a RFC 3339 timestamp or duration ago",
+			EnvVar: "PLUGIN_SINCE",
+		},
+		cli.StringFlag{
+			Name:   "status",
+			Usage:  "publish the message as a commit status or check run",
+			EnvVar: "PLUGIN_STATUS",
+		},
+		cli.StringFlag{
+			Name:   "status-context",
+			Usage:  "name of the commit status or check run",
+			Value:  "github-comment",
+			EnvVar: "PLUGIN_STATUS_CONTEXT",
+		},
+		cli.StringFlag{
+			Name:   "status-title",
+			Usage:  "title of the status, defaults to the first line of the message",
+			EnvVar: "PLUGIN_STATUS_TITLE",
+		},
+		cli.StringFlag{
+			Name:   "status-annotations-file",
+			Usage:  "JSON file of check run annotations",
+			EnvVar: "PLUGIN_STATUS_ANNOTATIONS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "template",
+			Usage:  "go template to render the comment message",
+			EnvVar: "PLUGIN_TEMPLATE",
+		},
+		cli.StringSliceFlag{
+			Name:   "on-status",
+			Usage:  "only comment when the build status is success, failure or changed",
+			EnvVar: "PLUGIN_ON_STATUS",
+		},
+		cli.StringSliceFlag{
+			Name:   "paths",
+			Usage:  "only comment when changed files match these globs",
+			EnvVar: "PLUGIN_PATHS",
+		},
+		cli.StringSliceFlag{
+			Name:   "paths-ignore",
+			Usage:  "ignore changed files matching these globs",
+			EnvVar: "PLUGIN_PATHS_IGNORE",
+		},
+		cli.StringSliceFlag{
+			Name:   "metadata",
+			Usage:  "KEY=VALUE fields to store in the comment metadata",
+			EnvVar: "PLUGIN_METADATA",
+		},
 
 		//
-		// drone env
+		// build env, defaults to the detected environment
 		//
 
 		cli.StringFlag{
-			Name:   "repo-name",
-			Usage:  "repository name",
-			EnvVar: "DRONE_REPO_NAME",
+			Name:  "repo-name",
+			Usage: "repository name",
+		},
+		cli.StringFlag{
+			Name:  "repo-owner",
+			Usage: "repository owner",
+		},
+		cli.StringFlag{
+			Name:  "repo-link",
+			Usage: "repository link",
 		},
 		cli.StringFlag{
-			Name:   "repo-owner",
-			Usage:  "repository owner",
-			EnvVar: "DRONE_REPO_OWNER",
+			Name:   "source-repo",
+			Usage:  "repository the PR comes from",
+			EnvVar: "PLUGIN_SOURCE_REPO",
+		},
+		cli.IntFlag{
+			Name:  "build-number",
+			Usage: "build number",
+		},
+		cli.StringFlag{
+			Name:  "build-status",
+			Usage: "build status",
+		},
+		cli.StringFlag{
+			Name:  "prev-build-status",
+			Usage: "previous build status",
+		},
+		cli.StringFlag{
+			Name:  "build-link",
+			Usage: "build link",
+		},
+		cli.StringFlag{
+			Name:  "commit-sha",
+			Usage: "git commit sha",
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:   "post-pending",
+			Usage:  "post the pending comments of fork PRs with trusted credentials",
+			Flags:  app.Flags,
+			Action: postPending,
 		},
 	}
 
@@ -97,25 +410,50 @@ func run(c *cli.Context) error {
 		"Revision": revision,
 	}).Info("Drone Github Comment Plugin Version")
 
-	// Read message from file
-	message := c.String("message")
-	if message == "" {
-		if _, err := os.Stat(c.String("message-file")); err == nil {
-			dat, err := ioutil.ReadFile(c.String("message-file"))
-
-			if err != nil {
-				return err
-			}
-
-			message = string(dat)
-		}
+	if c.Bool("print-env") {
+		return plugin.DetectEnv(os.Getenv).Print(os.Stdout)
 	}
 
 	p, err := plugin.NewFromCLI(c)
 	if err != nil {
 		return err
 	}
-	p.Message = message
 
-	return p.Exec()
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	return p.ExecContext(ctx)
+}
+
+func postPending(c *cli.Context) error {
+	logrus.WithFields(logrus.Fields{
+		"Revision": revision,
+	}).Info("Drone Github Comment Plugin Version")
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	return plugin.PostPendingFromCLIContext(ctx, c)
+}
+
+// signalContext returns a context cancelled on SIGTERM or SIGINT. Drone stops a
+// cancelled build with SIGTERM, give up in-flight requests.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		select {
+		case s := <-signals:
+			logrus.WithField("signal", s).Warn("Cancelling")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
 }
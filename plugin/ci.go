@@ -0,0 +1,239 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// woodpeckerEnv reads the CI_ variables of Woodpecker, using the pipeline
+// names of 1.x and the build names of 0.15
+func woodpeckerEnv(l envLookup) BuildEnv {
+	env := BuildEnv{sources: l.sources}
+
+	if l.getenv("CI") != "woodpecker" {
+		return env
+	}
+
+	env.System = "woodpecker"
+	env.Version = l.getenv("CI_SYSTEM_VERSION")
+
+	env.Event = l.first("event", "CI_PIPELINE_EVENT", "CI_BUILD_EVENT")
+	env.RepoOwner = l.first("repo-owner", "CI_REPO_OWNER")
+	env.RepoName = l.first("repo-name", "CI_REPO_NAME")
+	l.repo(&env, "CI_REPO")
+	env.RepoLink = l.first("repo-link", "CI_REPO_URL", "CI_REPO_LINK", "CI_REPO_CLONE_URL")
+	env.IssueNum = l.number("issue-num", "CI_COMMIT_PULL_REQUEST")
+
+	if env.IssueNum == 0 {
+		env.IssueNum = l.pullRef("CI_COMMIT_REF")
+	}
+
+	env.BuildNumber = l.number("build-number", "CI_PIPELINE_NUMBER", "CI_BUILD_NUMBER")
+	env.BuildStatus = l.first("build-status", "CI_PIPELINE_STATUS", "CI_BUILD_STATUS")
+	env.PrevBuildStatus = l.first("prev-build-status", "CI_PREV_PIPELINE_STATUS", "CI_PREV_BUILD_STATUS")
+	env.BuildLink = l.first("build-link", "CI_PIPELINE_URL", "CI_BUILD_LINK")
+	env.CommitSHA = l.first("commit-sha", "CI_COMMIT_SHA")
+
+	return env
+}
+
+// githubActionsEnv reads the GITHUB_ variables and the event payload of GitHub
+// Actions. GITHUB_SHA is the merge commit for pull requests, so the head commit
+// is taken from the payload. The job status is not in the environment, so the
+// build status must be set.
+func githubActionsEnv(l envLookup) BuildEnv {
+	env := BuildEnv{sources: l.sources}
+
+	if l.getenv("GITHUB_ACTIONS") != "true" {
+		return env
+	}
+
+	env.System = "github-actions"
+
+	env.Event = l.first("event", "GITHUB_EVENT_NAME")
+	l.repo(&env, "GITHUB_REPOSITORY")
+
+	server := strings.TrimSuffix(l.getenv("GITHUB_SERVER_URL"), "/")
+	if server != "" && env.RepoOwner != "" {
+		env.RepoLink = server + "/" + env.RepoOwner + "/" + env.RepoName
+		l.sources["repo-link"] = "GITHUB_SERVER_URL"
+
+		if run := l.getenv("GITHUB_RUN_ID"); run != "" {
+			env.BuildLink = env.RepoLink + "/actions/runs/" + run
+			l.sources["build-link"] = "GITHUB_RUN_ID"
+		}
+	}
+
+	env.BaseURL = l.first("base-url", "GITHUB_API_URL")
+	env.BuildNumber = l.number("build-number", "GITHUB_RUN_NUMBER")
+	env.CommitSHA = l.first("commit-sha", "GITHUB_SHA")
+
+	if path := l.getenv("GITHUB_EVENT_PATH"); path != "" {
+		l.githubEvent(&env, path)
+	}
+
+	if env.IssueNum == 0 {
+		env.IssueNum = l.pullRef("GITHUB_REF")
+	}
+
+	return env
+}
+
+// githubEvent reads the PR of a GitHub Actions event payload
+func (l envLookup) githubEvent(env *BuildEnv, path string) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return
+	}
+
+	var event struct {
+		PullRequest *struct {
+			Number int `json:"number"`
+			Head   struct {
+				SHA  string `json:"sha"`
+				Repo struct {
+					FullName string `json:"full_name"`
+				} `json:"repo"`
+			} `json:"head"`
+		} `json:"pull_request"`
+		Issue *struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+	}
+
+	if err := json.Unmarshal(data, &event); err != nil {
+		return
+	}
+
+	switch {
+	case event.PullRequest != nil:
+		env.IssueNum = event.PullRequest.Number
+		l.sources["issue-num"] = "GITHUB_EVENT_PATH"
+
+		if sha := event.PullRequest.Head.SHA; sha != "" {
+			env.CommitSHA = sha
+			l.sources["commit-sha"] = "GITHUB_EVENT_PATH"
+		}
+
+		if repo := event.PullRequest.Head.Repo.FullName; repo != "" {
+			env.SourceRepo = repo
+			l.sources["source-repo"] = "GITHUB_EVENT_PATH"
+		}
+	case event.Issue != nil:
+		// comments on PRs are issue_comment events
+		env.IssueNum = event.Issue.Number
+		l.sources["issue-num"] = "GITHUB_EVENT_PATH"
+	}
+}
+
+// gitlabEnv reads the CI_ variables of GitLab CI for external GitHub
+// repositories. The project URL is on GitLab, so the repository link is not
+// set.
+func gitlabEnv(l envLookup) BuildEnv {
+	env := BuildEnv{sources: l.sources}
+
+	if l.getenv("GITLAB_CI") != "true" {
+		return env
+	}
+
+	env.System = "gitlab"
+	env.Version = l.getenv("CI_SERVER_VERSION")
+
+	env.Event = l.first("event", "CI_PIPELINE_SOURCE")
+	l.repo(&env, "CI_EXTERNAL_PULL_REQUEST_TARGET_REPOSITORY")
+	if env.RepoOwner == "" {
+		env.RepoOwner = l.first("repo-owner", "CI_PROJECT_NAMESPACE")
+		env.RepoName = l.first("repo-name", "CI_PROJECT_NAME")
+	}
+
+	env.SourceRepo = l.first("source-repo", "CI_EXTERNAL_PULL_REQUEST_SOURCE_REPOSITORY")
+	env.IssueNum = l.number("issue-num", "CI_EXTERNAL_PULL_REQUEST_IID")
+	env.BuildNumber = l.number("build-number", "CI_PIPELINE_IID")
+	env.BuildLink = l.first("build-link", "CI_PIPELINE_URL")
+	env.CommitSHA = l.first("commit-sha", "CI_EXTERNAL_PULL_REQUEST_SOURCE_BRANCH_SHA", "CI_COMMIT_SHA")
+
+	// GitLab reports failed instead of failure
+	switch status := l.first("build-status", "CI_JOB_STATUS"); status {
+	case "failed":
+		env.BuildStatus = "failure"
+	default:
+		env.BuildStatus = status
+	}
+
+	return env
+}
+
+// jenkinsEnv reads the variables of Jenkins with the GitHub Branch Source
+// plugin, which sets CHANGE_ for pull requests. The result of the build is not
+// in the environment, so the build status must be set.
+func jenkinsEnv(l envLookup) BuildEnv {
+	env := BuildEnv{sources: l.sources}
+
+	if l.getenv("JENKINS_URL") == "" {
+		return env
+	}
+
+	env.System = "jenkins"
+	env.Version = l.getenv("JENKINS_VERSION")
+
+	env.IssueNum = l.number("issue-num", "CHANGE_ID")
+	env.Event = "push"
+	if env.IssueNum != 0 {
+		env.Event = "pull_request"
+	}
+
+	// https://github.com/owner/name/pull/12 or the clone URL
+	for _, name := range []string{"CHANGE_URL", "GIT_URL"} {
+		link, owner, repo := parseRepoURL(l.getenv(name))
+
+		if link == "" {
+			continue
+		}
+
+		env.RepoOwner, env.RepoName, env.RepoLink = owner, repo, link
+		l.sources["repo-owner"] = name
+		l.sources["repo-name"] = name
+		l.sources["repo-link"] = name
+		break
+	}
+
+	// CHANGE_FORK is the owner or owner/name of the fork
+	if fork := l.first("source-repo", "CHANGE_FORK"); fork != "" {
+		if !strings.Contains(fork, "/") {
+			fork += "/" + env.RepoName
+		}
+
+		env.SourceRepo = fork
+	}
+
+	env.BuildNumber = l.number("build-number", "BUILD_NUMBER")
+	env.BuildLink = l.first("build-link", "BUILD_URL")
+	env.CommitSHA = l.first("commit-sha", "GIT_COMMIT")
+
+	return env
+}
+
+// parseRepoURL returns the web link, owner and name of a repository, pull
+// request or clone URL, including scp like git@host:owner/name.git
+func parseRepoURL(value string) (string, string, string) {
+	scheme, host, path := "https", "", ""
+
+	if u, err := url.Parse(value); err == nil && u.Host != "" {
+		if u.Scheme == "http" {
+			scheme = u.Scheme
+		}
+		host, path = u.Host, u.Path
+	} else if i := strings.Index(value, ":"); i > 0 && strings.Contains(value[:i], "@") {
+		host, path = value[strings.Index(value, "@")+1:i], value[i+1:]
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if host == "" || len(parts) < 2 {
+		return "", "", ""
+	}
+
+	return scheme + "://" + host + "/" + parts[0] + "/" + parts[1], parts[0], parts[1]
+}
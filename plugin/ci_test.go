@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestCI(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("CI environments", func() {
+		g.It("reads Woodpecker before its Drone variables", func() {
+			env := DetectEnv(readEnv("../testdata/env/woodpecker.env"))
+
+			g.Assert(env.System).Equal("woodpecker")
+			g.Assert(env.Version).Equal("1.0.2")
+			g.Assert(env.Event).Equal("pull_request")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("https://github.com/octocat/hello-world")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.BuildNumber).Equal(31)
+			g.Assert(env.BuildStatus).Equal("failure")
+			g.Assert(env.PrevBuildStatus).Equal("success")
+			g.Assert(env.BuildLink).Equal("https://ci.example.com/repos/1/pipeline/31")
+			g.Assert(env.CommitSHA).Equal("d8dbe4d94f15fe89232e0402c6e8a0ddf21af3ab")
+		})
+
+		g.It("reads GitHub Actions and the event payload", func() {
+			env := DetectEnv(readEnv("../testdata/env/github-actions.env"))
+
+			g.Assert(env.System).Equal("github-actions")
+			g.Assert(env.Event).Equal("pull_request")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("https://github.com/octocat/hello-world")
+			g.Assert(env.SourceRepo).Equal("someone/hello-world")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.BuildNumber).Equal(17)
+			g.Assert(env.BuildLink).Equal("https://github.com/octocat/hello-world/actions/runs/1658821493")
+			g.Assert(env.CommitSHA).Equal("d8dbe4d94f15fe89232e0402c6e8a0ddf21af3ab")
+			g.Assert(env.BaseURL).Equal("https://api.github.com")
+			g.Assert(env.BuildStatus).Equal("")
+			g.Assert(env.sources["issue-num"]).Equal("GITHUB_EVENT_PATH")
+		})
+
+		g.It("falls back to the GitHub Actions ref without a payload", func() {
+			vars := readEnv("../testdata/env/github-actions.env")
+			env := DetectEnv(func(name string) string {
+				if name == "GITHUB_EVENT_PATH" {
+					return "../testdata/env/missing.json"
+				}
+
+				return vars(name)
+			})
+
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.CommitSHA).Equal("a1b2c3d4e5f60718293a4b5c6d7e8f9012345678")
+			g.Assert(env.sources["issue-num"]).Equal("GITHUB_REF")
+		})
+
+		g.It("reads GitLab CI for external GitHub pull requests", func() {
+			env := DetectEnv(readEnv("../testdata/env/gitlab.env"))
+
+			g.Assert(env.System).Equal("gitlab")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("")
+			g.Assert(env.SourceRepo).Equal("someone/hello-world")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.BuildNumber).Equal(9)
+			g.Assert(env.BuildStatus).Equal("failure")
+			g.Assert(env.CommitSHA).Equal("d8dbe4d94f15fe89232e0402c6e8a0ddf21af3ab")
+		})
+
+		g.It("reads Jenkins GitHub Branch Source", func() {
+			env := DetectEnv(readEnv("../testdata/env/jenkins.env"))
+
+			g.Assert(env.System).Equal("jenkins")
+			g.Assert(env.Event).Equal("pull_request")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("https://github.com/octocat/hello-world")
+			g.Assert(env.SourceRepo).Equal("someone/hello-world")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.BuildNumber).Equal(5)
+			g.Assert(env.BuildLink).Equal("https://jenkins.example.com/job/hello-world/job/PR-42/5/")
+			g.Assert(env.CommitSHA).Equal("d8dbe4d94f15fe89232e0402c6e8a0ddf21af3ab")
+		})
+
+		g.It("reads Jenkins branch builds from the clone URL", func() {
+			env := DetectEnv(func(name string) string {
+				return map[string]string{
+					"JENKINS_URL": "https://jenkins.example.com/",
+					"GIT_URL":     "git@github.com:octocat/hello-world.git",
+				}[name]
+			})
+
+			g.Assert(env.Event).Equal("push")
+			g.Assert(env.IssueNum).Equal(0)
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("https://github.com/octocat/hello-world")
+		})
+	})
+}
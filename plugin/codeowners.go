@@ -0,0 +1,234 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// codeownersPaths are the locations GitHub reads CODEOWNERS from, in order
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type (
+	codeownersRule struct {
+		pattern string
+		owners  []string
+	}
+
+	// ownership maps the changed files of a PR to their code owners
+	ownership struct {
+		rules  []codeownersRule
+		files  []string
+		author string
+		max    int
+
+		mentioned map[string]bool
+	}
+)
+
+// ownership fetches CODEOWNERS at the PR base ref and the changed files of the PR
+func (p Plugin) ownership(ctx context.Context) (*ownership, error) {
+	if p.gitClient == nil {
+		return nil, fmt.Errorf("ownership(): git client not initialized")
+	}
+
+	pr, _, err := p.gitClient.PullRequests.Get(ctx, p.RepoOwner, p.RepoName, p.IssueNum)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := p.changedFiles(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	o := &ownership{
+		files:     files,
+		author:    pr.GetUser().GetLogin(),
+		max:       p.MaxMentions,
+		mentioned: map[string]bool{},
+	}
+
+	opts := &github.RepositoryContentGetOptions{Ref: pr.GetBase().GetRef()}
+	for _, path := range codeownersPaths {
+		file, _, _, err := p.gitClient.Repositories.GetContents(ctx, p.RepoOwner, p.RepoName, path, opts)
+
+		if isNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := file.GetContent()
+
+		if err != nil {
+			return nil, err
+		}
+
+		o.rules = parseCodeowners(content)
+		return o, nil
+	}
+
+	p.log().Warn("No CODEOWNERS file found, mentions will be empty")
+	return o, nil
+}
+
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		rules = append(rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+	}
+
+	return rules
+}
+
+// matches reports whether a CODEOWNERS pattern matches the file, following
+// gitignore rules
+func (r codeownersRule) matches(file string) bool {
+	pattern := r.pattern
+
+	if strings.HasPrefix(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		pattern = "**/" + pattern
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		return matchGlob(pattern+"**", file)
+	}
+
+	// Only a literal last segment can name a directory, docs/* does not own
+	// docs/build/x.md
+	if strings.ContainsAny(pattern[strings.LastIndex(pattern, "/")+1:], "*?[") {
+		return matchGlob(pattern, file)
+	}
+
+	return matchGlob(pattern, file) || matchGlob(pattern+"/**", file)
+}
+
+// owners returns the owners of a file, the last matching rule wins
+func (o *ownership) owners(file string) []string {
+	if o == nil {
+		return nil
+	}
+
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].matches(file) {
+			return o.rules[i].owners
+		}
+	}
+
+	return nil
+}
+
+// all returns the unique owners of every changed file
+func (o *ownership) all() []string {
+	if o == nil {
+		return nil
+	}
+
+	var all []string
+	for _, file := range o.files {
+		for _, owner := range o.owners(file) {
+			if !contains(all, owner) {
+				all = append(all, owner)
+			}
+		}
+	}
+
+	return all
+}
+
+// mention renders owners as mentions. Once the mention cap is reached further
+// owners are rendered without `@` so they are not notified.
+func (o *ownership) mention(owners []string) string {
+	return strings.Join(o.capped(owners), " ")
+}
+
+// capped returns owners with the `@` removed from those past the mention cap
+func (o *ownership) capped(owners []string) []string {
+	if o == nil {
+		return nil
+	}
+
+	var mentions []string
+	for _, owner := range owners {
+		name := strings.TrimPrefix(owner, "@")
+
+		if !strings.HasPrefix(owner, "@") {
+			// email owners can not be mentioned
+			mentions = append(mentions, owner)
+			continue
+		}
+
+		if !o.mentioned[name] && o.max > 0 && len(o.mentioned) >= o.max {
+			mentions = append(mentions, name)
+			continue
+		}
+
+		o.mentioned[name] = true
+		mentions = append(mentions, owner)
+	}
+
+	return mentions
+}
+
+// reviewers returns the users and team slugs owning the changed files,
+// excluding the PR author and capped at the mention limit
+func (o *ownership) reviewers() ([]string, []string) {
+	var users, teams []string
+
+	for _, owner := range o.all() {
+		if o.max > 0 && len(users)+len(teams) >= o.max {
+			break
+		}
+
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+
+		name := strings.TrimPrefix(owner, "@")
+		if i := strings.Index(name, "/"); i >= 0 {
+			teams = append(teams, name[i+1:])
+		} else if name != o.author {
+			users = append(users, name)
+		}
+	}
+
+	return users, teams
+}
+
+// requestReviews requests reviews from the owners of the changed files
+func (p Plugin) requestReviews(ctx context.Context, o *ownership) error {
+	users, teams := o.reviewers()
+
+	if len(users) == 0 && len(teams) == 0 {
+		p.log().Info("Skipped requesting reviews because the changed files have no owners")
+		return nil
+	}
+
+	_, _, err := p.gitClient.PullRequests.RequestReviewers(ctx, p.RepoOwner, p.RepoName, p.IssueNum, github.ReviewersRequest{
+		Reviewers:     users,
+		TeamReviewers: teams,
+	})
+	return err
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCodeowners(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("codeowners rules", func() {
+		rules := parseCodeowners(`
+# comment
+*.go          @gophers
+/build/logs   @ops
+docs/         @docs-writer # trailing comment
+/api/*        @api
+`)
+
+		g.It("parses rules", func() {
+			g.Assert(len(rules)).Equal(4)
+			g.Assert(rules[2].owners).Equal([]string{"@docs-writer"})
+		})
+
+		g.It("matches like gitignore", func() {
+			g.Assert(rules[0].matches("services/api/main.go")).IsTrue()
+			g.Assert(rules[1].matches("build/logs/out.log")).IsTrue()
+			g.Assert(rules[1].matches("src/build/logs/out.log")).IsFalse()
+			g.Assert(rules[2].matches("src/docs/index.md")).IsTrue()
+			g.Assert(rules[2].matches("docs.md")).IsFalse()
+			g.Assert(rules[3].matches("api/main.go")).IsTrue()
+			g.Assert(rules[3].matches("api/build/x.md")).IsFalse()
+		})
+
+		g.It("does not match nested files with a trailing glob", func() {
+			rule := codeownersRule{pattern: "docs/*"}
+
+			g.Assert(rule.matches("docs/index.md")).IsTrue()
+			g.Assert(rule.matches("docs/build/x.md")).IsFalse()
+		})
+
+		g.It("uses the last matching rule", func() {
+			o := &ownership{rules: rules}
+
+			g.Assert(o.owners("docs/main.go")).Equal([]string{"@docs-writer"})
+		})
+
+		g.It("caps mentions", func() {
+			o := &ownership{max: 2, mentioned: map[string]bool{}}
+
+			g.Assert(o.mention([]string{"@a", "@b", "@c", "@a"})).Equal("@a @b c @a")
+		})
+
+		g.It("caps the owners of templates", func() {
+			o := &ownership{rules: rules, files: []string{"main.go", "docs/index.md", "api/main.go"}, max: 2, mentioned: map[string]bool{}}
+			p := Plugin{Template: "{{ range .Owners }}{{ . }} {{ end }}"}
+
+			message, err := p.render(nil, o)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("@gophers @docs-writer api ")
+		})
+	})
+
+	g.Describe("mention owners", func() {
+		pl := Plugin{
+			BaseURL:        "http://server.com",
+			Codeowners:     true,
+			Message:        "test message",
+			IssueNum:       12,
+			RepoName:       "test-repo",
+			RepoOwner:      "test-org",
+			RequestReviews: true,
+			Template:       "Please check {{ mentions .Owners }}",
+			Token:          "fake",
+		}
+
+		g.It("requires codeowners to request reviews", func() {
+			pl := pl
+			pl.Codeowners = false
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that codeowners is required")
+		})
+
+		g.It("mentions and requests reviews from owners of changed files", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/pulls/12").
+				Reply(200).
+				File("../testdata/response/pull.json")
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/pulls/12/files").
+				Reply(200).
+				File("../testdata/response/pull-files.json")
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/contents/.github/CODEOWNERS").
+				MatchParam("ref", "master").
+				Reply(200).
+				File("../testdata/response/codeowners.json")
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				BodyString("Please check @docs-writer someone@example.com @api-dev @octocat").
+				Reply(201).
+				JSON(map[string]string{})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/pulls/12/requested_reviewers").
+				BodyString(`{"reviewers":\["docs-writer","api-dev"\]}`).
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+	})
+}
@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const commentsPerPage = 100
+
+type (
+	// commentIndex caches the comment listing of issues. An issue is listed once
+	// per run and kept current with the changes of the run. On later runs pages
+	// are revalidated with their ETag so unchanged pages do not use rate limit.
+	commentIndex struct {
+		mu     sync.Mutex
+		issues map[int][]*commentPage
+		fresh  map[int]bool
+	}
+
+	commentPage struct {
+		etag     string
+		next     int
+		last     int
+		comments []*github.IssueComment
+	}
+)
+
+func newCommentIndex() *commentIndex {
+	return &commentIndex{
+		issues: map[int][]*commentPage{},
+		fresh:  map[int]bool{},
+	}
+}
+
+// invalidate starts a new run, cached pages are revalidated on next use
+func (idx *commentIndex) invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.fresh = map[int]bool{}
+}
+
+// list returns all comments of an issue, fetching or revalidating the pages
+// once per run
+func (idx *commentIndex) list(ctx context.Context, p Plugin, issue int) ([]*github.IssueComment, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.fresh[issue] {
+		if err := idx.fetch(ctx, p, issue); err != nil {
+			return nil, err
+		}
+	}
+
+	var comments []*github.IssueComment
+	for _, page := range idx.issues[issue] {
+		comments = append(comments, page.comments...)
+	}
+
+	return comments, nil
+}
+
+func (idx *commentIndex) fetch(ctx context.Context, p Plugin, issue int) error {
+	cached := idx.issues[issue]
+
+	var pages []*commentPage
+	for number := 1; ; {
+		var page *commentPage
+		if len(pages) < len(cached) {
+			page = cached[len(pages)]
+		}
+
+		page, err := p.fetchCommentPage(ctx, issue, number, page)
+		if err != nil {
+			return err
+		}
+
+		pages = append(pages, page)
+		if page.next == 0 {
+			break
+		}
+		number = page.next
+	}
+
+	idx.issues[issue] = pages
+	idx.fresh[issue] = true
+
+	return nil
+}
+
+// add records a comment created by this run
+func (idx *commentIndex) add(issue int, comment *github.IssueComment) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	pages := idx.issues[issue]
+	if len(pages) == 0 {
+		// never listed, it will be fetched on first use
+		return
+	}
+
+	// the page may have overflowed on the server, so always refetch it
+	last := pages[len(pages)-1]
+	if last == nil {
+		return
+	}
+	last.comments = append(last.comments, comment)
+	last.etag = ""
+}
+
+// replace records a comment edited by this run
+func (idx *commentIndex) replace(issue int, comment *github.IssueComment) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, page := range idx.issues[issue] {
+		if page == nil {
+			continue
+		}
+
+		for i, c := range page.comments {
+			if c.GetID() == comment.GetID() {
+				page.comments[i] = comment
+				return
+			}
+		}
+	}
+}
+
+// remove records a comment deleted by this run
+func (idx *commentIndex) remove(issue int, id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	pages := idx.issues[issue]
+	for n, page := range pages {
+		if page == nil {
+			continue
+		}
+
+		for i, c := range page.comments {
+			if c.GetID() != id {
+				continue
+			}
+
+			page.comments = append(page.comments[:i], page.comments[i+1:]...)
+
+			// later comments shift to earlier pages on the server
+			for _, later := range pages[n:] {
+				if later != nil {
+					later.etag = ""
+				}
+			}
+			return
+		}
+	}
+}
+
+// fetchCommentPage fetches a page of comments, returning cached if unchanged
+func (p Plugin) fetchCommentPage(ctx context.Context, issue, number int, cached *commentPage) (*commentPage, error) {
+	u := fmt.Sprintf("repos/%v/%v/issues/%d/comments?per_page=%d&page=%d", p.RepoOwner, p.RepoName, issue, commentsPerPage, number)
+	if !p.Since.IsZero() {
+		u += "&since=" + url.QueryEscape(p.Since.UTC().Format(time.RFC3339))
+	}
+
+	req, err := p.gitClient.NewRequest("GET", u, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The ETag only covers the body, comments added after a full last page go
+	// to a new page without changing it
+	full := cached != nil && cached.next == 0 && len(cached.comments) >= commentsPerPage
+
+	if cached != nil && cached.etag != "" && !full {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	var comments []*github.IssueComment
+	resp, err := p.gitClient.Do(ctx, req, &comments)
+
+	if resp != nil && resp.StatusCode == http.StatusNotModified && cached != nil {
+		if resp.Header.Get("Link") != "" {
+			cached.next, cached.last = resp.NextPage, resp.LastPage
+		}
+
+		return cached, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &commentPage{
+		etag:     resp.Header.Get("ETag"),
+		next:     resp.NextPage,
+		last:     resp.LastPage,
+		comments: comments,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestComments(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("comment index", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Update:    true,
+			Token:     "fake",
+		}
+
+		g.It("lists comments once per run", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/non-existing-comment.json")
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7, "body": "test message\n<!-- id: 123 -->\n"})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			// Created comment is found without listing again
+			comment, err := p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(comment.GetID()).Equal(int64(7))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("revalidates pages with their ETag on later runs", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				SetHeader("ETag", `"abc"`).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				Reply(200).
+				JSON(map[string]interface{}{"id": 7, "body": "test message\n<!-- id: 123 -->\n"})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchHeader("If-None-Match", `"abc"`).
+				Reply(304)
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				Reply(200).
+				JSON(map[string]interface{}{"id": 7, "body": "test message\n<!-- id: 123 -->\n"})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("refetches a full last page for comments on a new page", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			var full []map[string]interface{}
+			for i := 1; i <= commentsPerPage; i++ {
+				full = append(full, map[string]interface{}{"id": i, "body": "other"})
+			}
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				SetHeader("ETag", `"abc"`).
+				JSON(full)
+
+			comment, err := p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(comment == nil).IsTrue()
+
+			// the page is unchanged, the new comment is on page 2
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchHeader("If-None-Match", `"abc"`).
+				Reply(304)
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "1").
+				Reply(200).
+				SetHeader("ETag", `"abc"`).
+				SetHeader("Link", `<http://server.com/repos/test-org/test-repo/issues/12/comments?per_page=100&page=2>; rel="next", <http://server.com/repos/test-org/test-repo/issues/12/comments?per_page=100&page=2>; rel="last"`).
+				JSON(full)
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "2").
+				Reply(200).
+				JSON([]map[string]interface{}{{"id": 200, "body": "test message\n<!-- id: 123 -->\n"}})
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/200").
+				Reply(200).
+				JSON(map[string]interface{}{"id": 200, "body": "test message\n<!-- id: 123 -->\n"})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	statusSuccess = "success"
+	statusFailure = "failure"
+	statusChanged = "changed"
+)
+
+// skipReason returns why the comment should not be posted, empty if it should be
+func (p Plugin) skipReason() (string, error) {
+	if !p.statusMatches() {
+		return fmt.Sprintf("build status %q does not match on_status %v", p.BuildStatus, p.OnStatus), nil
+	}
+
+	if len(p.Paths) == 0 && len(p.PathsIgnore) == 0 {
+		return "", nil
+	}
+
+	files, err := p.changedFiles(p.gitContext)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !pathsMatch(files, p.Paths, p.PathsIgnore) {
+		return fmt.Sprintf("none of the %d changed files match paths %v (ignoring %v)", len(files), p.Paths, p.PathsIgnore), nil
+	}
+
+	return "", nil
+}
+
+func (p Plugin) statusMatches() bool {
+	if len(p.OnStatus) == 0 {
+		return true
+	}
+
+	for _, status := range p.OnStatus {
+		switch status {
+		case statusSuccess, statusFailure:
+			if p.BuildStatus == status {
+				return true
+			}
+		case statusChanged:
+			if p.BuildStatus != p.PrevBuildStatus {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p Plugin) changedFiles(ctx context.Context) ([]string, error) {
+	if p.gitClient == nil {
+		return nil, fmt.Errorf("changedFiles(): git client not initialized")
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+
+	// get all pages of results
+	var files []string
+	for {
+		page, resp, err := p.gitClient.PullRequests.ListFiles(ctx, p.RepoOwner, p.RepoName, p.IssueNum, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range page {
+			files = append(files, f.GetFilename())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return files, nil
+}
+
+// pathsMatch returns true if any file not matched by ignore is matched by include.
+// An empty include list matches every file.
+func pathsMatch(files, include, ignore []string) bool {
+	for _, file := range files {
+		if matchAny(ignore, file) {
+			continue
+		}
+
+		if len(include) == 0 || matchAny(include, file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlob matches name against a path.Match pattern where `**` matches any
+// number of directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestConditions(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("matchGlob", func() {
+		g.It("matches single segments", func() {
+			g.Assert(matchGlob("docs/*.md", "docs/index.md")).IsTrue()
+			g.Assert(matchGlob("docs/*.md", "docs/api/index.md")).IsFalse()
+		})
+
+		g.It("matches any number of directories with **", func() {
+			g.Assert(matchGlob("services/**", "services/api/main.go")).IsTrue()
+			g.Assert(matchGlob("**/*.go", "main.go")).IsTrue()
+			g.Assert(matchGlob("**/*.go", "services/api/main.go")).IsTrue()
+			g.Assert(matchGlob("**/*.go", "docs/index.md")).IsFalse()
+		})
+	})
+
+	g.Describe("statusMatches", func() {
+		g.It("matches everything without on_status", func() {
+			g.Assert(Plugin{BuildStatus: "failure"}.statusMatches()).IsTrue()
+		})
+
+		g.It("matches the build status", func() {
+			p := Plugin{BuildStatus: "failure", OnStatus: []string{"failure"}}
+			g.Assert(p.statusMatches()).IsTrue()
+
+			p.BuildStatus = "success"
+			g.Assert(p.statusMatches()).IsFalse()
+		})
+
+		g.It("matches a changed status", func() {
+			p := Plugin{BuildStatus: "success", PrevBuildStatus: "failure", OnStatus: []string{"changed"}}
+			g.Assert(p.statusMatches()).IsTrue()
+
+			p.PrevBuildStatus = "success"
+			g.Assert(p.statusMatches()).IsFalse()
+		})
+	})
+
+	g.Describe("conditional comment", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		g.It("rejects an invalid on_status", func() {
+			pl := pl
+			pl.OnStatus = []string{"sometimes"}
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error for invalid on_status")
+		})
+
+		g.It("requires the build status for on_status", func() {
+			pl := pl
+			pl.OnStatus = []string{"failure"}
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that the build status is required")
+		})
+
+		g.It("skips when build status does not match", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.BuildStatus = "success"
+			pl.OnStatus = []string{"failure"}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			// We do not expect this endpoint to get called
+			gock.New("http://server.com").
+				Post("/repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsFalse()
+		})
+
+		g.It("comments when changed files match paths", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Paths = []string{"services/**"}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("/repos/test-org/test-repo/pulls/12/files").
+				Reply(200).
+				File("../testdata/response/pull-files.json")
+
+			gock.New("http://server.com").
+				Post("/repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("skips when all changed files are ignored", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.PathsIgnore = []string{"docs/**", "services/api/*.go"}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("/repos/test-org/test-repo/pulls/12/files").
+				Reply(200).
+				File("../testdata/response/pull-files.json")
+
+			// We do not expect this endpoint to get called
+			gock.New("http://server.com").
+				Post("/repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsFalse()
+		})
+	})
+}
@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigFile is the config file loaded from the workspace if present
+const DefaultConfigFile = ".github-comment.yml"
+
+type (
+	// Config is the config file format, see github-comment.schema.json
+	Config struct {
+		Defaults map[string]interface{}            `yaml:"defaults"`
+		Profiles map[string]map[string]interface{} `yaml:"profiles"`
+	}
+)
+
+// configIgnored are settings that can not be set from the config file. PRs can
+// change it, so nothing deciding where credentials are sent is allowed.
+var configIgnored = map[string]bool{
+	"config":             true,
+	"profile":            true,
+	"api-key":            true,
+	"api-key-file":       true,
+	"base-url":           true,
+	"ca-cert":            true,
+	"client-cert":        true,
+	"client-key":         true,
+	"credential-command": true,
+	"fork-mode":          true,
+	"source-repo":        true,
+	"netrc-file":         true,
+	"print-env":          true,
+	"proxy":              true,
+	"skip-verify":        true,
+	"username":           true,
+	"password":           true,
+}
+
+// LoadConfig reads a config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config file %s. %s", path, err)
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("Failed to parse config file %s. %s", path, err)
+	}
+
+	return config, nil
+}
+
+// Settings returns the defaults merged with the named profile, the profile
+// taking precedence
+func (c Config) Settings(profile string) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+
+	for k, v := range c.Defaults {
+		settings[k] = v
+	}
+
+	if profile == "" {
+		return settings, nil
+	}
+
+	values, ok := c.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("Profile %q not found in config file", profile)
+	}
+
+	for k, v := range values {
+		settings[k] = v
+	}
+
+	return settings, nil
+}
+
+// applyConfig sets flags not set on the command line or environment from the
+// config file. Precedence is command line, environment, profile, defaults.
+func applyConfig(c *cli.Context) error {
+	path := c.String("config")
+
+	if _, err := os.Stat(path); os.IsNotExist(err) && !c.IsSet("config") {
+		if c.String("profile") != "" {
+			return fmt.Errorf("Config file %s is required to use a profile", path)
+		}
+		return nil
+	}
+
+	config, err := LoadConfig(path)
+
+	if err != nil {
+		return err
+	}
+
+	settings, err := config.Settings(c.String("profile"))
+
+	if err != nil {
+		return err
+	}
+
+	// plugin setting flag names mapped to whether they take a list
+	flags := map[string]bool{}
+	for _, f := range c.App.Flags {
+		if env := reflect.ValueOf(f).FieldByName("EnvVar"); !env.IsValid() || !strings.Contains(env.String(), "PLUGIN_") {
+			continue
+		}
+
+		switch f.(type) {
+		case cli.StringSliceFlag, cli.IntSliceFlag, cli.Int64SliceFlag:
+			flags[strings.Split(f.GetName(), ",")[0]] = true
+		default:
+			flags[strings.Split(f.GetName(), ",")[0]] = false
+		}
+	}
+
+	// apply in a stable order so errors are deterministic
+	var keys []string
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.Replace(key, "_", "-", -1)
+
+		list, ok := flags[name]
+
+		if !ok || configIgnored[name] {
+			return fmt.Errorf("Setting %q is not allowed in config file %s", key, path)
+		}
+
+		if c.IsSet(name) {
+			logrus.WithField("setting", key).Debug("Config file value overridden")
+			continue
+		}
+
+		values, err := configValues(settings[key])
+
+		if err == nil && len(values) > 1 && !list {
+			err = fmt.Errorf("expected a single value")
+		}
+
+		if err != nil {
+			return fmt.Errorf("Invalid value for setting %q in config file %s. %s", key, path, err)
+		}
+
+		for _, value := range values {
+			if err := c.Set(name, value); err != nil {
+				return fmt.Errorf("Invalid value for setting %q in config file %s. %s", key, path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// configValues converts a config value to flag values. Lists set a value per
+// item and maps set a KEY=VALUE per entry.
+func configValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			switch item.(type) {
+			case []interface{}, map[interface{}]interface{}:
+				return nil, fmt.Errorf("nested values are not supported")
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case map[interface{}]interface{}:
+		var values []string
+		for k, item := range v {
+			values = append(values, fmt.Sprintf("%v=%v", k, item))
+		}
+		sort.Strings(values)
+		return values, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/urfave/cli"
+)
+
+func TestConfig(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	context := func(args ...string) *cli.Context {
+		app := cli.NewApp()
+		app.Flags = []cli.Flag{
+			cli.StringFlag{Name: "config", Value: DefaultConfigFile, EnvVar: "PLUGIN_CONFIG"},
+			cli.StringFlag{Name: "profile", EnvVar: "PLUGIN_PROFILE"},
+			cli.StringFlag{Name: "api-key", EnvVar: "PLUGIN_API_KEY"},
+			cli.StringFlag{Name: "key", EnvVar: "PLUGIN_KEY"},
+			cli.StringFlag{Name: "template", EnvVar: "PLUGIN_TEMPLATE"},
+			cli.BoolFlag{Name: "update", EnvVar: "PLUGIN_UPDATE"},
+			cli.StringSliceFlag{Name: "on-status", EnvVar: "PLUGIN_ON_STATUS"},
+			cli.StringSliceFlag{Name: "labels-add", EnvVar: "PLUGIN_LABELS_ADD"},
+			cli.StringSliceFlag{Name: "metadata", EnvVar: "PLUGIN_METADATA"},
+			cli.StringFlag{Name: "repo-name", EnvVar: "DRONE_REPO_NAME"},
+			cli.StringFlag{Name: "base-url", EnvVar: "PLUGIN_BASE_URL"},
+			cli.StringFlag{Name: "ca-cert", EnvVar: "PLUGIN_CA_CERT"},
+			cli.StringFlag{Name: "client-cert", EnvVar: "PLUGIN_CLIENT_CERT"},
+			cli.StringFlag{Name: "client-key", EnvVar: "PLUGIN_CLIENT_KEY"},
+			cli.StringFlag{Name: "proxy", EnvVar: "PLUGIN_PROXY"},
+			cli.BoolFlag{Name: "skip-verify", EnvVar: "PLUGIN_SKIP_VERIFY"},
+		}
+
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range app.Flags {
+			f.Apply(set)
+		}
+		set.Parse(args)
+
+		return cli.NewContext(app, set, nil)
+	}
+
+	g.Describe("applyConfig", func() {
+		g.It("ignores a missing default config file", func() {
+			c := context()
+
+			g.Assert(applyConfig(c) == nil).IsTrue()
+		})
+
+		g.It("errors for a missing config file that was set", func() {
+			c := context("--config", "../testdata/config/missing.yml")
+
+			g.Assert(applyConfig(c) != nil).IsTrue("should have received error for missing config file")
+		})
+
+		g.It("applies defaults", func() {
+			c := context("--config", "../testdata/config/github-comment.yml")
+
+			g.Assert(applyConfig(c) == nil).IsTrue()
+			g.Assert(c.Bool("update")).IsTrue()
+			g.Assert(c.StringSlice("on-status")).Equal([]string{"failure"})
+			g.Assert(c.StringSlice("labels-add")).Equal([]string{"tests-failing"})
+		})
+
+		g.It("applies the profile over defaults", func() {
+			c := context("--config", "../testdata/config/github-comment.yml", "--profile", "coverage")
+
+			g.Assert(applyConfig(c) == nil).IsTrue()
+			g.Assert(c.String("key")).Equal("coverage")
+			g.Assert(c.StringSlice("on-status")).Equal([]string{"success", "failure"})
+			g.Assert(c.StringSlice("metadata")).Equal([]string{"coverage=87%"})
+		})
+
+		g.It("keeps values set on the command line", func() {
+			c := context("--config", "../testdata/config/github-comment.yml", "--profile", "coverage", "--key", "override")
+
+			g.Assert(applyConfig(c) == nil).IsTrue()
+			g.Assert(c.String("key")).Equal("override")
+		})
+
+		g.It("errors for an unknown profile", func() {
+			c := context("--config", "../testdata/config/github-comment.yml", "--profile", "missing")
+
+			g.Assert(applyConfig(c) != nil).IsTrue("should have received error for unknown profile")
+		})
+
+		g.It("does not allow drone environment", func() {
+			c := context("--config", "../testdata/config/drone-env.yml")
+
+			g.Assert(applyConfig(c) != nil).IsTrue("should have received error for drone environment in config file")
+		})
+
+		g.It("does not allow credentials", func() {
+			c := context("--config", "../testdata/config/invalid.yml")
+
+			g.Assert(applyConfig(c) != nil).IsTrue("should have received error for credentials in config file")
+		})
+
+		for setting, value := range map[string]string{
+			"base_url":    "https://evil.example.com",
+			"ca_cert":     "evil.pem",
+			"client_cert": "evil.pem",
+			"client_key":  "evil.key",
+			"proxy":       "http://evil.example.com:3128",
+			"skip_verify": "true",
+		} {
+			setting, value := setting, value
+
+			g.It(fmt.Sprintf("does not allow %s", setting), func() {
+				dir, _ := ioutil.TempDir("", "github-comment")
+				defer os.RemoveAll(dir)
+
+				path := filepath.Join(dir, "github-comment.yml")
+				ioutil.WriteFile(path, []byte(fmt.Sprintf("defaults:\n  %s: %s\n", setting, value)), 0644)
+
+				c := context("--config", path)
+
+				err := applyConfig(c)
+				g.Assert(err != nil).IsTrue(fmt.Sprintf("should have received error for %s in config file", setting))
+				g.Assert(c.IsSet(strings.Replace(setting, "_", "-", -1))).IsFalse()
+			})
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultRequestTimeout limits a single GitHub request when no request timeout
+// is set, so a hung server does not stall the build forever
+const defaultRequestTimeout = 30 * time.Second
+
+// withTimeout returns ctx limited by the timeout of the plugin, if any
+func (p Plugin) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if p.Timeout > 0 {
+		return context.WithTimeout(ctx, p.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// timedOut explains err if it was caused by ctx being done. Only the timeout of
+// ctx is reported as timed out, ctx is done without it when parent is.
+func (p Plugin) timedOut(parent, ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case parent.Err() == context.Canceled:
+		return fmt.Errorf("Cancelled. %s", err)
+	case parent.Err() == nil && ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("Timed out after %s. %s", p.Timeout, err)
+	}
+
+	return err
+}
+
+func (p Plugin) requestTimeout() time.Duration {
+	if p.RequestTimeout > 0 {
+		return p.RequestTimeout
+	}
+
+	return defaultRequestTimeout
+}
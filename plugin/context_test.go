@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+func TestContext(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("context", func() {
+		// server never answers until the request is given up
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+
+		g.After(func() {
+			server.Close()
+		})
+
+		pl := Plugin{
+			BaseURL:   server.URL,
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Update:    true,
+			Token:     "fake",
+		}
+
+		g.It("stops when the context is cancelled", func() {
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			err = p.ExecContext(ctx)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.HasPrefix(err.Error(), "Cancelled.")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("stops when the timeout expires", func() {
+			pl := pl
+			pl.Timeout = 50 * time.Millisecond
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.CommentContext(context.Background())
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.HasPrefix(err.Error(), "Timed out after 50ms.")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("does not report the deadline of the context as a timeout", func() {
+			pl := pl
+			pl.Timeout = time.Minute
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err = p.ExecContext(ctx)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.Contains(err.Error(), "Timed out")).IsFalse(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("limits a single request", func() {
+			pl := pl
+			pl.RequestTimeout = 50 * time.Millisecond
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			start := time.Now()
+			err = p.Exec()
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(time.Since(start) < time.Second).IsTrue(fmt.Sprintf("Took %s", time.Since(start)))
+		})
+
+		g.It("rejects a negative timeout", func() {
+			pl := pl
+			pl.Timeout = -time.Second
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+}
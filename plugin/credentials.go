@@ -0,0 +1,296 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	masked = "****"
+
+	// minSecretLength avoids masking short values found everywhere
+	minSecretLength = 4
+)
+
+// loadCredentials fills missing credentials from the configured sources. The
+// first source providing credentials wins: api key, api key file, credential
+// command, username and password, netrc file.
+func (p *Plugin) loadCredentials() error {
+	if p.Token == "" && p.TokenFile != "" {
+		data, err := ioutil.ReadFile(p.TokenFile)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read API key file %s. %s", p.TokenFile, err)
+		}
+
+		p.Token = strings.TrimSpace(string(data))
+
+		if p.Token == "" {
+			return fmt.Errorf("API key file %s is empty", p.TokenFile)
+		}
+	}
+
+	if p.Token == "" && p.CredentialCommand != "" {
+		token, err := p.runCredentialCommand()
+
+		if err != nil {
+			return err
+		}
+
+		p.Token = token
+	}
+
+	if p.Token == "" && (p.Username == "" || p.Password == "") && p.NetrcFile != "" {
+		login, password, err := readNetrc(p.NetrcFile, netrcHosts(p.baseURL()))
+
+		if err != nil {
+			return err
+		}
+
+		if login != "" && password != "" {
+			p.Username, p.Password = login, password
+		}
+	}
+
+	return nil
+}
+
+// runCredentialCommand runs the credential command with the shell, its output
+// is the token
+func (p Plugin) runCredentialCommand() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.requestTimeout())
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.CredentialCommand)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Failed to run credential command. %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	token := strings.TrimSpace(stdout.String())
+
+	if token == "" {
+		return "", fmt.Errorf("Credential command printed no API key")
+	}
+
+	return token, nil
+}
+
+// netrcHosts returns the netrc machines to look up for base, github.com
+// credentials also apply to its API host
+func netrcHosts(base string) []string {
+	u, err := url.Parse(base)
+
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "api.github.com" {
+		return []string{host, "github.com"}
+	}
+
+	return []string{host}
+}
+
+// readNetrc returns the login and password of the first host with an entry in
+// a netrc file, or of the default entry
+func readNetrc(path string, hosts []string) (string, string, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to read netrc file %s. %s", path, err)
+	}
+
+	type entry struct {
+		login, password string
+	}
+
+	var (
+		machines = map[string]*entry{}
+		current  *entry
+		fallback *entry
+		macro    bool
+		key      string
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// macro definitions run until an empty line
+		if macro {
+			macro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			switch key {
+			case "machine":
+				current = &entry{}
+				if name := strings.ToLower(field); machines[name] == nil {
+					machines[name] = current
+				}
+			case "login":
+				if current != nil {
+					current.login = field
+				}
+			case "password":
+				if current != nil {
+					current.password = field
+				}
+			case "account":
+			case "macdef":
+				macro = true
+			default:
+				if field == "default" {
+					current = &entry{}
+					fallback = current
+				}
+				key = field
+				continue
+			}
+
+			key = ""
+			if macro {
+				break
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		if e, ok := machines[host]; ok {
+			return e.login, e.password, nil
+		}
+	}
+
+	if fallback != nil {
+		return fallback.login, fallback.password, nil
+	}
+
+	return "", "", nil
+}
+
+// DefaultNetrcFile returns the .netrc of the home directory if it exists
+func DefaultNetrcFile() string {
+	home := os.Getenv("HOME")
+
+	if home == "" {
+		return ""
+	}
+
+	path := filepath.Join(home, ".netrc")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+
+	return path
+}
+
+// secrets returns the credentials of the plugin. The username is a token for
+// OAuth basic auth.
+func (p Plugin) secrets() []string {
+	values := []string{p.Token, p.Password}
+	if p.Password == "x-oauth-basic" {
+		values = []string{p.Token, p.Username}
+	}
+
+	var secrets []string
+	for _, v := range values {
+		if len(v) >= minSecretLength {
+			secrets = append(secrets, v)
+		}
+	}
+
+	return secrets
+}
+
+// mask replaces the credentials of the plugin in s
+func (p Plugin) mask(s string) string {
+	for _, secret := range p.secrets() {
+		s = strings.Replace(s, secret, masked, -1)
+	}
+
+	return s
+}
+
+// maskError returns err with the credentials of the plugin masked
+func (p Plugin) maskError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if msg := p.mask(err.Error()); msg != err.Error() {
+		return errors.New(msg)
+	}
+
+	return err
+}
+
+type maskFormatter struct {
+	logrus.Formatter
+
+	mu      sync.Mutex
+	secrets map[string]bool
+}
+
+// Format masks the secrets in the formatted entry
+func (f *maskFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data, err := f.Formatter.Format(entry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for secret := range f.secrets {
+		data = bytes.Replace(data, []byte(secret), []byte(masked), -1)
+	}
+
+	return data, nil
+}
+
+// maskLogs masks the credentials of the plugin in everything its logger writes
+func (p Plugin) maskLogs() {
+	var logger *logrus.Logger
+
+	switch l := p.log().(type) {
+	case *logrus.Logger:
+		logger = l
+	case *logrus.Entry:
+		logger = l.Logger
+	default:
+		p.log().Warn("Credentials can not be masked in logs of a custom logger")
+		return
+	}
+
+	f, ok := logger.Formatter.(*maskFormatter)
+	if !ok {
+		f = &maskFormatter{Formatter: logger.Formatter, secrets: map[string]bool{}}
+		logger.Formatter = f
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, secret := range p.secrets() {
+		f.secrets[secret] = true
+	}
+}
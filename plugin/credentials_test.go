@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCredentials(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("credentials", func() {
+		var dir string
+
+		g.Before(func() {
+			dir, _ = ioutil.TempDir("", "github-comment")
+		})
+
+		g.After(func() {
+			os.RemoveAll(dir)
+		})
+
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+		}
+
+		g.It("reads the api key from a file", func() {
+			file := filepath.Join(dir, "token")
+			ioutil.WriteFile(file, []byte("file-token\n"), 0600)
+
+			pl := pl
+			pl.TokenFile = file
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.Token).Equal("file-token")
+		})
+
+		g.It("fails on a missing api key file", func() {
+			pl := pl
+			pl.TokenFile = filepath.Join(dir, "missing")
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("prefers the api key", func() {
+			pl := pl
+			pl.Token = "direct-token"
+			pl.TokenFile = filepath.Join(dir, "missing")
+			pl.CredentialCommand = "exit 1"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.Token).Equal("direct-token")
+		})
+
+		g.It("runs the credential command", func() {
+			pl := pl
+			pl.CredentialCommand = "echo command-token"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.Token).Equal("command-token")
+		})
+
+		g.It("fails when the credential command fails", func() {
+			pl := pl
+			pl.CredentialCommand = "echo vault is sealed >&2; exit 2"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.Contains(err.Error(), "vault is sealed")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("reads the netrc machine of the host", func() {
+			file := filepath.Join(dir, "netrc")
+			ioutil.WriteFile(file, []byte(`machine other.com login other password other-pass
+
+macdef init
+machine server.com login fake password fake
+
+default login default-user password default-pass
+machine server.com
+  login netrc-user
+  password netrc-pass
+`), 0600)
+
+			pl := pl
+			pl.NetrcFile = file
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.Username).Equal("netrc-user")
+			g.Assert(p.Password).Equal("netrc-pass")
+		})
+
+		g.It("reads github.com netrc entries for the API host", func() {
+			login, password, err := readNetrc("../testdata/netrc", netrcHosts("https://api.github.com/"))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(login).Equal("github-token")
+			g.Assert(password).Equal("x-oauth-basic")
+		})
+
+		g.It("falls back to the default netrc entry", func() {
+			login, password, err := readNetrc("../testdata/netrc", netrcHosts("https://ghe.example.com/"))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(login).Equal("default-user")
+			g.Assert(password).Equal("default-pass")
+		})
+	})
+
+	g.Describe("masking", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "secret-token",
+		}
+
+		g.It("masks credentials in errors", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(401).
+				JSON(map[string]interface{}{"message": "Bad credentials secret-token"})
+
+			err = p.Exec()
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.Contains(err.Error(), "secret-token")).IsFalse(fmt.Sprintf("Received err: %s", err))
+			g.Assert(strings.Contains(err.Error(), "Bad credentials ****")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("masks credentials in logs", func() {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.Out = &buf
+
+			p, err := NewFromPlugin(pl, WithLogger(logger))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			p.log().WithField("token", "secret-token").Info("Using secret-token")
+			g.Assert(strings.Contains(buf.String(), "secret-token")).IsFalse(fmt.Sprintf("Logged: %s", buf.String()))
+			g.Assert(strings.Count(buf.String(), "****")).Equal(2)
+		})
+
+		g.It("masks the username of OAuth basic auth", func() {
+			pl := pl
+			pl.Token = ""
+			pl.Username = "oauth-token"
+			pl.Password = "x-oauth-basic"
+
+			g.Assert(pl.mask("login oauth-token")).Equal("login ****")
+		})
+	})
+}
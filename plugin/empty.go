@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	emptyFail   = "fail"
+	emptySkip   = "skip"
+	emptyDelete = "delete"
+)
+
+// needsMessage returns true if this run posts the message somewhere
+func (p Plugin) needsMessage() bool {
+	return !p.SkipComment || p.StatusTarget != ""
+}
+
+func isEmpty(message string) bool {
+	return strings.TrimSpace(message) == ""
+}
+
+// handleEmpty applies the on_empty policy when there is nothing to post,
+// returning the keyed comment left after it
+func (p Plugin) handleEmpty(comment *github.IssueComment) (*github.IssueComment, error) {
+	if p.OnEmpty != emptyDelete {
+		p.log().Info("Skipped posting because the message is empty")
+		return comment, nil
+	}
+
+	if comment == nil {
+		p.log().Info("Skipped deleting because there is no comment to delete")
+		return nil, nil
+	}
+
+	p.log().WithField("comment", comment.GetID()).Info("Deleting comment because the message is empty")
+	if _, err := p.gitClient.Issues.DeleteComment(p.gitContext, p.RepoOwner, p.RepoName, int(comment.GetID())); err != nil {
+		return nil, err
+	}
+
+	p.comments.remove(p.IssueNum, comment.GetID())
+	return nil, nil
+}
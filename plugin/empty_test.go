@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestEmpty(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("empty message", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   " \n",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Update:    true,
+			Token:     "fake",
+		}
+
+		g.It("fails by default", func() {
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error for empty message")
+		})
+
+		g.It("fails when the template renders nothing", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Template = "{{ if .Previous }}{{ .Message }}{{ end }}"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/non-existing-comment.json")
+
+			err = p.Exec()
+			g.Assert(err != nil).IsTrue("should have received error for empty message")
+		})
+
+		g.It("skips posting", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnEmpty = "skip"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("deletes the existing comment", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnEmpty = "delete"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Delete("repos/test-org/test-repo/issues/comments/7").
+				Reply(204)
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+	})
+}
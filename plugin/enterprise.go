@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultBaseURL   = "https://api.github.com/"
+	defaultUploadURL = "https://uploads.github.com/"
+)
+
+// baseURL returns the base URL to use, falling back to the host of the
+// repository link when unset
+func (p Plugin) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+
+	if u, err := url.Parse(p.RepoLink); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/"
+	}
+
+	return defaultBaseURL
+}
+
+// endpoints returns the REST API and upload URLs for base. Hosts other than
+// github.com are GitHub Enterprise, their API is served under /api/v3/ and
+// uploads under /api/uploads/. GraphQL is derived from the API URL.
+func endpoints(base string) (*url.URL, *url.URL, error) {
+	u, err := url.Parse(base)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse base URL. %s", err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, nil, fmt.Errorf("Invalid base URL %q, must be a URL like https://github.example.com", base)
+	}
+
+	switch strings.ToLower(u.Hostname()) {
+	case "github.com", "api.github.com":
+		api, _ := url.Parse(defaultBaseURL)
+		upload, _ := url.Parse(defaultUploadURL)
+		return api, upload, nil
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	for _, suffix := range []string{"/api/v3", "/api/graphql", "/api/uploads", "/api"} {
+		if strings.HasSuffix(path, suffix) {
+			path = strings.TrimSuffix(path, suffix)
+			break
+		}
+	}
+
+	api := *u
+	api.Path = path + "/api/v3/"
+	api.RawPath = ""
+
+	upload := *u
+	upload.Path = path + "/api/uploads/"
+	upload.RawPath = ""
+
+	return &api, &upload, nil
+}
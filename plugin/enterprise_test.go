@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestEnterprise(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("endpoints", func() {
+		cases := []struct {
+			base   string
+			api    string
+			upload string
+		}{
+			{"https://api.github.com", "https://api.github.com/", "https://uploads.github.com/"},
+			{"https://github.com/", "https://api.github.com/", "https://uploads.github.com/"},
+			{"https://ghe.example.com", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+			{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+			{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+			{"https://ghe.example.com/api/graphql", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+			{"https://proxy.example.com/github/", "https://proxy.example.com/github/api/v3/", "https://proxy.example.com/github/api/uploads/"},
+		}
+
+		for _, c := range cases {
+			c := c
+			g.It(fmt.Sprintf("normalizes %s", c.base), func() {
+				api, upload, err := endpoints(c.base)
+				g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+				g.Assert(api.String()).Equal(c.api)
+				g.Assert(upload.String()).Equal(c.upload)
+			})
+		}
+
+		g.It("rejects a host without scheme", func() {
+			_, _, err := endpoints("ghe.example.com")
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+
+	g.Describe("base url", func() {
+		pl := Plugin{
+			Message:   "test message",
+			IssueNum:  12,
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		g.It("defaults to github.com", func() {
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.BaseURL).Equal("https://api.github.com/")
+		})
+
+		g.It("falls back to the repo link host", func() {
+			pl := pl
+			pl.RepoLink = "https://ghe.example.com/test-org/test-repo"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.BaseURL).Equal("https://ghe.example.com/api/v3/")
+		})
+
+		g.It("prefers the base url", func() {
+			pl := pl
+			pl.BaseURL = "https://other.example.com"
+			pl.RepoLink = "https://ghe.example.com/test-org/test-repo"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.BaseURL).Equal("https://other.example.com/api/v3/")
+		})
+
+		g.It("uses the enterprise GraphQL endpoint", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.BaseURL = "http://ghe.example.com"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(p.graphqlURL()).Equal("http://ghe.example.com/api/graphql")
+
+			gock.New("http://ghe.example.com").
+				Post("/api/v3/repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+)
+
+type (
+	// BuildEnv is the build described by the environment of the CI system,
+	// normalized across systems and versions
+	BuildEnv struct {
+		System          string
+		Version         string
+		Event           string
+		BaseURL         string
+		RepoOwner       string
+		RepoName        string
+		RepoLink        string
+		SourceRepo      string
+		IssueNum        int
+		BuildNumber     int
+		BuildStatus     string
+		PrevBuildStatus string
+		BuildLink       string
+		CommitSHA       string
+
+		// sources maps flag names to the variable their value was read from
+		sources map[string]string
+	}
+
+	// envLookup reads variables, recording which one each value came from
+	envLookup struct {
+		getenv  func(string) string
+		sources map[string]string
+	}
+)
+
+var (
+	// pullRefPattern matches the refs GitHub builds pull requests from
+	pullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/(head|merge)$`)
+
+	// getenv reads the environment, replaced in tests
+	getenv = os.Getenv
+)
+
+// envAdapters read the environment of a CI system, the first one that
+// detects its system is used. Woodpecker also sets some Drone variables, so it
+// comes first.
+var envAdapters = []func(envLookup) BuildEnv{
+	woodpeckerEnv,
+	githubActionsEnv,
+	gitlabEnv,
+	jenkinsEnv,
+	droneEnv,
+}
+
+// DetectEnv reads the build from the environment
+func DetectEnv(getenv func(string) string) BuildEnv {
+	for _, adapter := range envAdapters {
+		if env := adapter(envLookup{getenv: getenv, sources: map[string]string{}}); env.System != "" {
+			return env
+		}
+	}
+
+	return BuildEnv{}
+}
+
+// droneEnv reads the variables of Drone 0.8, 1.x and 2.x. 1.x renamed the
+// owner to the namespace and added the source repository and HTTP clone URL,
+// the PR number is only set for pull_request events.
+func droneEnv(l envLookup) BuildEnv {
+	env := BuildEnv{sources: l.sources}
+
+	if l.getenv("DRONE") == "" && l.getenv("DRONE_REPO_NAME") == "" && l.getenv("DRONE_REPO") == "" {
+		return env
+	}
+
+	env.System = "drone"
+	switch {
+	case strings.HasPrefix(l.getenv("DRONE_SYSTEM_VERSION"), "2."):
+		env.Version = "2.x"
+	case strings.HasPrefix(l.getenv("DRONE_SYSTEM_VERSION"), "1."), l.getenv("DRONE_REPO_NAMESPACE") != "":
+		env.Version = "1.x"
+	default:
+		env.Version = "0.8"
+	}
+
+	env.Event = l.first("event", "DRONE_BUILD_EVENT", "DRONE_EVENT")
+	env.RepoOwner = l.first("repo-owner", "DRONE_REPO_NAMESPACE", "DRONE_REPO_OWNER")
+	env.RepoName = l.first("repo-name", "DRONE_REPO_NAME")
+
+	// DRONE_REPO is owner/name in all versions
+	l.repo(&env, "DRONE_REPO")
+
+	env.RepoLink = l.first("repo-link", "DRONE_REPO_LINK", "DRONE_GIT_HTTP_URL", "DRONE_REMOTE_URL")
+	env.SourceRepo = l.first("source-repo", "DRONE_SOURCE_REPO")
+	env.IssueNum = l.number("issue-num", "DRONE_PULL_REQUEST")
+
+	// Some runners only set the ref of pull requests
+	if env.IssueNum == 0 {
+		env.IssueNum = l.pullRef("DRONE_COMMIT_REF")
+	}
+
+	env.BuildNumber = l.number("build-number", "DRONE_BUILD_NUMBER")
+	env.BuildStatus = l.first("build-status", "DRONE_BUILD_STATUS")
+	env.PrevBuildStatus = l.first("prev-build-status", "DRONE_PREV_BUILD_STATUS")
+	env.BuildLink = l.first("build-link", "DRONE_BUILD_LINK")
+	env.CommitSHA = l.first("commit-sha", "DRONE_COMMIT_SHA", "DRONE_COMMIT")
+
+	return env
+}
+
+// first returns the first set variable of names
+func (l envLookup) first(flag string, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(l.getenv(name)); value != "" {
+			l.sources[flag] = name
+			return value
+		}
+	}
+
+	return ""
+}
+
+// number returns the first set variable of names that is a positive number
+func (l envLookup) number(flag string, names ...string) int {
+	for _, name := range names {
+		if n, err := strconv.Atoi(strings.TrimSpace(l.getenv(name))); err == nil && n > 0 {
+			l.sources[flag] = name
+			return n
+		}
+	}
+
+	return 0
+}
+
+// pullRef returns the number of a refs/pull/ variable
+func (l envLookup) pullRef(name string) int {
+	m := pullRefPattern.FindStringSubmatch(l.getenv(name))
+
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	l.sources["issue-num"] = name
+	return n
+}
+
+// split returns the owner and name of an owner/name variable
+func (l envLookup) split(name string) (string, string) {
+	return splitRepo(l.getenv(name))
+}
+
+// repo sets the owner and name from the first set owner/name variable that are
+// not set yet
+func (l envLookup) repo(env *BuildEnv, names ...string) {
+	for _, name := range names {
+		owner, repo := l.split(name)
+
+		if owner == "" {
+			continue
+		}
+
+		if env.RepoOwner == "" {
+			env.RepoOwner = owner
+			l.sources["repo-owner"] = name
+		}
+
+		if env.RepoName == "" {
+			env.RepoName = repo
+			l.sources["repo-name"] = name
+		}
+
+		return
+	}
+}
+
+// splitRepo returns the owner and name of owner/name
+func splitRepo(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	i := strings.LastIndex(value, "/")
+
+	if i <= 0 || i == len(value)-1 {
+		return "", ""
+	}
+
+	return value[:i], value[i+1:]
+}
+
+// values returns the settings of the environment by flag name
+func (env BuildEnv) values() map[string]string {
+	values := map[string]string{
+		"base-url":          env.BaseURL,
+		"repo-owner":        env.RepoOwner,
+		"repo-name":         env.RepoName,
+		"repo-link":         env.RepoLink,
+		"source-repo":       env.SourceRepo,
+		"build-status":      env.BuildStatus,
+		"prev-build-status": env.PrevBuildStatus,
+		"build-link":        env.BuildLink,
+		"commit-sha":        env.CommitSHA,
+	}
+
+	if env.IssueNum != 0 {
+		values["issue-num"] = strconv.Itoa(env.IssueNum)
+	}
+
+	if env.BuildNumber != 0 {
+		values["build-number"] = strconv.Itoa(env.BuildNumber)
+	}
+
+	return values
+}
+
+// Print writes the detected environment and the variables it was read from
+func (env BuildEnv) Print(w io.Writer) error {
+	system := "unknown"
+	if env.System != "" {
+		system = strings.TrimSpace(env.System + " " + env.Version)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "SETTING\tVALUE\tSOURCE\n")
+	fmt.Fprintf(tw, "system\t%s\t\n", system)
+	fmt.Fprintf(tw, "event\t%s\t%s\n", env.Event, env.sources["event"])
+
+	values := env.values()
+	for _, flag := range []string{"base-url", "repo-owner", "repo-name", "repo-link", "source-repo", "issue-num", "build-number", "build-status", "prev-build-status", "build-link", "commit-sha"} {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", flag, values[flag], env.sources[flag])
+	}
+
+	return tw.Flush()
+}
+
+// applyEnv sets flags that are not set from the build environment
+func applyEnv(c *cli.Context, env BuildEnv) error {
+	defined := map[string]bool{}
+	for _, f := range c.App.Flags {
+		defined[strings.Split(f.GetName(), ",")[0]] = true
+	}
+
+	for flag, value := range env.values() {
+		if value == "" || !defined[flag] || c.IsSet(flag) {
+			continue
+		}
+
+		if err := c.Set(flag, value); err != nil {
+			return fmt.Errorf("Invalid value for %s from %s. %s", flag, env.sources[flag], err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/urfave/cli"
+)
+
+// readEnv reads a KEY=VALUE file as getenv
+func readEnv(path string) func(string) string {
+	vars := map[string]string{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		vars[parts[0]] = parts[1]
+	}
+
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestEnv(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("DetectEnv", func() {
+		g.It("reads Drone 0.8", func() {
+			env := DetectEnv(readEnv("../testdata/env/drone-0.8.env"))
+
+			g.Assert(env.System).Equal("drone")
+			g.Assert(env.Version).Equal("0.8")
+			g.Assert(env.Event).Equal("pull_request")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.RepoLink).Equal("https://github.com/octocat/hello-world")
+			g.Assert(env.SourceRepo).Equal("")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.BuildNumber).Equal(22)
+			g.Assert(env.BuildStatus).Equal("failure")
+			g.Assert(env.PrevBuildStatus).Equal("success")
+			g.Assert(env.BuildLink).Equal("https://drone.example.com/octocat/hello-world/22")
+			g.Assert(env.CommitSHA).Equal("d8dbe4d94f15fe89232e0402c6e8a0ddf21af3ab")
+		})
+
+		g.It("reads Drone 1.x", func() {
+			env := DetectEnv(readEnv("../testdata/env/drone-1.env"))
+
+			g.Assert(env.Version).Equal("1.x")
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.SourceRepo).Equal("someone/hello-world")
+			g.Assert(env.IssueNum).Equal(42)
+			g.Assert(env.sources["repo-owner"]).Equal("DRONE_REPO_NAMESPACE")
+		})
+
+		g.It("reads Drone 2.x push builds without a PR", func() {
+			env := DetectEnv(readEnv("../testdata/env/drone-2.env"))
+
+			g.Assert(env.Version).Equal("2.x")
+			g.Assert(env.Event).Equal("push")
+			g.Assert(env.IssueNum).Equal(0)
+			g.Assert(env.BuildNumber).Equal(23)
+			g.Assert(env.CommitSHA).Equal("4a4b5c6d94f15fe89232e0402c6e8a0ddf21af3a")
+		})
+
+		g.It("falls back to the full repository name and the PR ref", func() {
+			env := DetectEnv(func(name string) string {
+				return map[string]string{
+					"DRONE":            "true",
+					"DRONE_REPO":       "octocat/hello-world",
+					"DRONE_COMMIT_REF": "refs/pull/7/head",
+				}[name]
+			})
+
+			g.Assert(env.RepoOwner).Equal("octocat")
+			g.Assert(env.RepoName).Equal("hello-world")
+			g.Assert(env.IssueNum).Equal(7)
+			g.Assert(env.sources["issue-num"]).Equal("DRONE_COMMIT_REF")
+		})
+
+		g.It("detects nothing outside of Drone", func() {
+			env := DetectEnv(func(string) string { return "" })
+
+			g.Assert(env.System).Equal("")
+			g.Assert(env.RepoName).Equal("")
+		})
+	})
+
+	g.Describe("print-env", func() {
+		g.It("prints the values and their variables", func() {
+			var buf bytes.Buffer
+
+			err := DetectEnv(readEnv("../testdata/env/drone-1.env")).Print(&buf)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			out := buf.String()
+			g.Assert(strings.HasPrefix(out, "SETTING            VALUE")).IsTrue(out)
+			g.Assert(strings.Contains(out, "system             drone 1.x")).IsTrue(out)
+			g.Assert(strings.Contains(out, "repo-owner         octocat")).IsTrue(out)
+			g.Assert(strings.Contains(out, "DRONE_REPO_NAMESPACE")).IsTrue(out)
+		})
+	})
+
+	g.Describe("applyEnv", func() {
+		context := func(args ...string) *cli.Context {
+			app := cli.NewApp()
+			app.Flags = []cli.Flag{
+				cli.IntFlag{Name: "issue-num", EnvVar: "PLUGIN_ISSUE_NUM"},
+				cli.StringFlag{Name: "repo-name"},
+				cli.StringFlag{Name: "repo-owner"},
+			}
+
+			set := flag.NewFlagSet("test", flag.ContinueOnError)
+			for _, f := range app.Flags {
+				f.Apply(set)
+			}
+			set.Parse(args)
+
+			return cli.NewContext(app, set, nil)
+		}
+
+		g.It("sets flags from the environment", func() {
+			c := context()
+
+			err := applyEnv(c, DetectEnv(readEnv("../testdata/env/drone-1.env")))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(c.String("repo-owner")).Equal("octocat")
+			g.Assert(c.String("repo-name")).Equal("hello-world")
+			g.Assert(c.Int("issue-num")).Equal(42)
+		})
+
+		g.It("keeps flags that are set", func() {
+			c := context("--issue-num", "7", "--repo-name", "other")
+
+			err := applyEnv(c, DetectEnv(readEnv("../testdata/env/drone-1.env")))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(c.String("repo-name")).Equal("other")
+			g.Assert(c.Int("issue-num")).Equal(7)
+		})
+	})
+}
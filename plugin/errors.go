@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// maxCommentLength is the longest comment body GitHub accepts
+const maxCommentLength = 65536
+
+type (
+	// APIError is a failed GitHub request with an explanation of the likely cause
+	APIError struct {
+		Method     string
+		Path       string
+		StatusCode int
+		RequestID  string
+		Message    string
+		Hint       string
+		Err        error
+	}
+)
+
+// Error returns the request, status, GitHub message, hint and request ID
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d", e.Method, e.Path, e.StatusCode)
+
+	if e.Message != "" {
+		msg += " " + e.Message
+	}
+
+	if e.Hint != "" {
+		msg += ". " + e.Hint
+	}
+
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (GitHub request ID %s)", e.RequestID)
+	}
+
+	return msg
+}
+
+// Unwrap returns the go-github error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// apiError explains go-github errors, other errors are returned as is
+func apiError(err error) error {
+	var (
+		resp    *http.Response
+		message string
+		hint    string
+	)
+
+	switch e := err.(type) {
+	case *github.ErrorResponse:
+		resp, message = e.Response, e.Message
+		hint = errorHint(e)
+	case *github.RateLimitError:
+		resp, message = e.Response, e.Message
+		hint = fmt.Sprintf("The API rate limit is exhausted, it resets at %s", e.Rate.Reset.UTC().Format("15:04:05 MST"))
+	case *github.AbuseRateLimitError:
+		resp, message = e.Response, e.Message
+		hint = "GitHub throttled the requests, retry later"
+		if e.RetryAfter != nil {
+			hint = fmt.Sprintf("GitHub throttled the requests, retry in %s", *e.RetryAfter)
+		}
+	default:
+		return err
+	}
+
+	if resp == nil || resp.Request == nil {
+		return err
+	}
+
+	return &APIError{
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.Path,
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-GitHub-Request-Id"),
+		Message:    message,
+		Hint:       hint,
+		Err:        err,
+	}
+}
+
+// errorHint explains the likely cause of an error response
+func errorHint(e *github.ErrorResponse) string {
+	header := e.Response.Header
+
+	switch e.Response.StatusCode {
+	case http.StatusUnauthorized:
+		return "The API key or password is invalid or expired, check api_key or username and password"
+	case http.StatusForbidden:
+		if missing := missingScopes(header); len(missing) > 0 {
+			return fmt.Sprintf("The API key is missing the %s scope, it has: %s", strings.Join(missing, " or "), scopesOrNone(header))
+		}
+		if header.Get("X-RateLimit-Remaining") == "0" {
+			return "The API rate limit is exhausted"
+		}
+		return "The identity is not allowed to do this, check its permissions on the repository"
+	case http.StatusNotFound:
+		hint := "The repository, issue or comment does not exist, or the identity has no access to it"
+		if header["X-Oauth-Scopes"] != nil && !hasScope(header, "repo") {
+			hint += fmt.Sprintf(". Private repositories need the repo scope, the API key has: %s", scopesOrNone(header))
+		}
+		return hint
+	case http.StatusGone:
+		return "Issues are disabled for the repository"
+	case http.StatusUnprocessableEntity:
+		details := errorDetails(e)
+
+		switch {
+		case strings.Contains(details, "locked"):
+			return "The conversation is locked, only collaborators can comment"
+		case strings.Contains(details, "too long"):
+			return fmt.Sprintf("The comment is too long, GitHub accepts at most %d characters", maxCommentLength)
+		}
+		return "GitHub rejected the request, check the settings and message"
+	}
+
+	return ""
+}
+
+// errorDetails returns the lower case message and errors of a response
+func errorDetails(e *github.ErrorResponse) string {
+	details := e.Message
+	for _, detail := range e.Errors {
+		details += " " + detail.Message + " " + detail.Code
+	}
+
+	return strings.ToLower(details)
+}
+
+// missingScopes returns the accepted scopes if the API key has none of them
+func missingScopes(header http.Header) []string {
+	var accepted []string
+	for _, scope := range strings.Split(header.Get("X-Accepted-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			accepted = append(accepted, scope)
+		}
+	}
+
+	if len(accepted) == 0 || header["X-Oauth-Scopes"] == nil {
+		return nil
+	}
+
+	for _, a := range accepted {
+		if hasScope(header, a) {
+			return nil
+		}
+	}
+
+	return accepted
+}
+
+// hasScope reports if the API key has scope
+func hasScope(header http.Header, scope string) bool {
+	for _, s := range strings.Split(header.Get("X-OAuth-Scopes"), ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func scopesOrNone(header http.Header) string {
+	if scopes := strings.TrimSpace(header.Get("X-OAuth-Scopes")); scopes != "" {
+		return scopes
+	}
+
+	return "none"
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestErrors(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("api errors", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		// post replies to creating the comment and returns the error of Exec
+		post := func(pl Plugin, status int, headers map[string]string, body map[string]interface{}) error {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			reply := gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(status).
+				SetHeader("X-GitHub-Request-Id", "AB12:CD34")
+			for k, v := range headers {
+				reply.SetHeader(k, v)
+			}
+			reply.JSON(body)
+
+			return p.Exec()
+		}
+
+		g.It("explains an invalid API key", func() {
+			err := post(pl, 401, nil, map[string]interface{}{"message": "Bad credentials"})
+			g.Assert(err.Error()).Equal("POST /api/v3/repos/test-org/test-repo/issues/12/comments: 401 Bad credentials. The API key or password is invalid or expired, check api_key or username and password (GitHub request ID AB12:CD34)")
+
+			apiErr, ok := err.(*APIError)
+			g.Assert(ok).IsTrue(fmt.Sprintf("Received %T", err))
+			g.Assert(apiErr.StatusCode).Equal(401)
+			g.Assert(apiErr.RequestID).Equal("AB12:CD34")
+		})
+
+		g.It("explains missing scopes", func() {
+			err := post(pl, 403, map[string]string{
+				"X-OAuth-Scopes":          "read:org",
+				"X-Accepted-OAuth-Scopes": "repo, public_repo",
+			}, map[string]interface{}{"message": "Resource not accessible by integration"})
+			g.Assert(strings.Contains(err.Error(), "The API key is missing the repo or public_repo scope, it has: read:org")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("explains missing permissions", func() {
+			err := post(pl, 403, map[string]string{
+				"X-OAuth-Scopes":          "repo",
+				"X-Accepted-OAuth-Scopes": "repo",
+			}, map[string]interface{}{"message": "Forbidden"})
+			g.Assert(strings.Contains(err.Error(), "The identity is not allowed to do this")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("explains a missing repository", func() {
+			err := post(pl, 404, map[string]string{"X-OAuth-Scopes": "public_repo"}, map[string]interface{}{"message": "Not Found"})
+			g.Assert(strings.Contains(err.Error(), "or the identity has no access to it. Private repositories need the repo scope, the API key has: public_repo")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("explains disabled issues", func() {
+			err := post(pl, 410, nil, map[string]interface{}{"message": "Issues are disabled for this repo"})
+			g.Assert(strings.Contains(err.Error(), "410 Issues are disabled for this repo. Issues are disabled for the repository")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("explains a locked conversation", func() {
+			pl := pl
+			pl.OnLocked = "post"
+
+			err := post(pl, 422, nil, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors":  []map[string]interface{}{{"resource": "IssueComment", "code": "unprocessable", "field": "data", "message": "Unable to create comment because issue is locked."}},
+			})
+			g.Assert(strings.Contains(err.Error(), "The conversation is locked")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("explains a too long comment", func() {
+			err := post(pl, 422, nil, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors":  []map[string]interface{}{{"resource": "IssueComment", "code": "custom", "field": "body", "message": "body is too long (maximum is 65536 characters)"}},
+			})
+			g.Assert(strings.Contains(err.Error(), "The comment is too long, GitHub accepts at most 65536 characters")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("returns other errors as is", func() {
+			err := fmt.Errorf("boom")
+			g.Assert(apiError(err) == err).IsTrue()
+			g.Assert(apiError(nil) == nil).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,257 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	forkPost     = "post"
+	forkSkip     = "skip"
+	forkArtifact = "artifact"
+
+	// DefaultArtifactFile is where pending comments of fork PRs are written
+	DefaultArtifactFile = "github-comment.pending.json"
+
+	pendingVersion = 1
+)
+
+type (
+	// PendingComment is a comment rendered by an untrusted fork PR build, to be
+	// posted by a trusted pipeline with post-pending
+	PendingComment struct {
+		Version    int               `json:"version"`
+		RepoOwner  string            `json:"repo_owner"`
+		RepoName   string            `json:"repo_name"`
+		Issue      int               `json:"issue"`
+		SourceRepo string            `json:"source_repo"`
+		Key        string            `json:"key"`
+		Update     bool              `json:"update"`
+		Build      int               `json:"build"`
+		Commit     string            `json:"commit"`
+		Fields     map[string]string `json:"fields,omitempty"`
+		Message    string            `json:"message"`
+	}
+)
+
+// IsFork reports if the build is for a PR from another repository
+func (p Plugin) IsFork() bool {
+	if p.IssueNum == 0 || p.SourceRepo == "" {
+		return false
+	}
+
+	source := repoFullName(p.SourceRepo)
+	return source != "" && source != strings.ToLower(p.RepoOwner+"/"+p.RepoName)
+}
+
+// forkSafe reports if the build must not use credentials because it is for a
+// fork PR
+func (p Plugin) forkSafe() bool {
+	return p.ForkMode != "" && p.ForkMode != forkPost && p.IsFork()
+}
+
+// execFork skips posting or writes the rendered comment to the artifact file
+func (p Plugin) execFork() error {
+	if p.ForkMode == forkSkip {
+		p.log().Infof("Skipped because the PR is from the fork %s", p.SourceRepo)
+		return nil
+	}
+
+	message, err := p.render(nil, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if isEmpty(message) {
+		if p.OnEmpty == "" || p.OnEmpty == emptyFail {
+			return fmt.Errorf("Refusing to post an empty comment")
+		}
+
+		p.log().Info("Skipped writing the pending comment because the message is empty")
+		return nil
+	}
+
+	pending := PendingComment{
+		Version:    pendingVersion,
+		RepoOwner:  p.RepoOwner,
+		RepoName:   p.RepoName,
+		Issue:      p.IssueNum,
+		SourceRepo: p.SourceRepo,
+		Key:        p.Key,
+		Update:     p.Update,
+		Build:      p.BuildNumber,
+		Commit:     p.CommitSHA,
+		Fields:     p.Metadata,
+		Message:    message,
+	}
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(p.artifactFile(), data, 0644); err != nil {
+		return fmt.Errorf("Failed to write pending comment %s. %s", p.artifactFile(), err)
+	}
+
+	p.log().WithField("file", p.artifactFile()).Info("Wrote pending comment for the fork PR, post it with post-pending")
+	return nil
+}
+
+func (p Plugin) artifactFile() string {
+	if p.ArtifactFile == "" {
+		return DefaultArtifactFile
+	}
+
+	return p.ArtifactFile
+}
+
+// ReadPending reads a pending comment file
+func ReadPending(path string) (*PendingComment, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read pending comment %s. %s", path, err)
+	}
+
+	pending := &PendingComment{}
+	if err := json.Unmarshal(data, pending); err != nil {
+		return nil, fmt.Errorf("Failed to parse pending comment %s. %s", path, err)
+	}
+
+	if pending.Version != pendingVersion {
+		return nil, fmt.Errorf("Unsupported pending comment version %d in %s", pending.Version, path)
+	}
+
+	return pending, nil
+}
+
+// apply sets the message of a pending comment on a trusted plugin after
+// checking it targets the same repository, PR and key. Everything else comes
+// from the trusted run, the file is written by an untrusted build.
+func (pending PendingComment) apply(p *Plugin) error {
+	if p.IssueNum == 0 {
+		return fmt.Errorf("You must provide the issue number to post pending comments")
+	}
+
+	// the same default key the fork build used
+	if p.Key == "" {
+		p.Key = defaultKey(*p)
+	}
+
+	if !strings.EqualFold(pending.RepoOwner, p.RepoOwner) || !strings.EqualFold(pending.RepoName, p.RepoName) {
+		return fmt.Errorf("Pending comment is for %s/%s, not %s/%s", pending.RepoOwner, pending.RepoName, p.RepoOwner, p.RepoName)
+	}
+
+	if pending.Issue != p.IssueNum {
+		return fmt.Errorf("Pending comment is for #%d, not #%d", pending.Issue, p.IssueNum)
+	}
+
+	if pending.Key != p.Key {
+		return fmt.Errorf("Pending comment has key %q, not %q", pending.Key, p.Key)
+	}
+
+	p.Message = pending.Message
+	p.Template = ""
+	p.Manifest = nil
+	p.ForkMode = forkPost
+
+	// Only post the comment, its content must not drive other writes
+	p.Codeowners = false
+	p.LabelsAdd = nil
+	p.LabelsRemove = nil
+	p.Reactions = nil
+	p.ReactionsRemove = nil
+	p.RequestReviews = false
+	p.Resolve = ""
+	p.StatusTarget = ""
+
+	return nil
+}
+
+// PostPendingFromCLI posts the pending comments of the artifact files, a glob,
+// with the trusted credentials of the command line
+func PostPendingFromCLI(c *cli.Context, opts ...Option) error {
+	return PostPendingFromCLIContext(context.Background(), c, opts...)
+}
+
+// PostPendingFromCLIContext posts pending comments like PostPendingFromCLI,
+// cancelling GitHub requests when ctx is done or the timeout expires
+func PostPendingFromCLIContext(ctx context.Context, c *cli.Context, opts ...Option) error {
+	base, err := pluginFromCLI(c)
+
+	if err != nil {
+		return err
+	}
+
+	pattern := base.artifactFile()
+	paths, err := filepath.Glob(pattern)
+
+	if err != nil {
+		return fmt.Errorf("Invalid artifact file pattern %s. %s", pattern, err)
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("No pending comments found at %s", pattern)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pending, err := ReadPending(path)
+
+		if err != nil {
+			return err
+		}
+
+		pp := base
+		if err := pending.apply(&pp); err != nil {
+			return fmt.Errorf("Refusing to post %s. %s", path, err)
+		}
+
+		p, err := NewFromPlugin(pp, opts...)
+
+		if err != nil {
+			return err
+		}
+
+		if err := p.ExecContext(ctx); err != nil {
+			return fmt.Errorf("Failed to post %s. %s", path, err)
+		}
+
+		p.log().WithField("file", path).Info("Posted pending comment")
+	}
+
+	return nil
+}
+
+// repoFullName returns the lower case owner/name of a repository name or
+// clone URL
+func repoFullName(repo string) string {
+	repo = strings.TrimSpace(repo)
+
+	if u, err := url.Parse(repo); err == nil && u.Host != "" {
+		repo = u.Path
+	} else if i := strings.Index(repo, ":"); i >= 0 && strings.Contains(repo[:i], "@") {
+		// scp like git@host:owner/name.git
+		repo = repo[i+1:]
+	}
+
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+
+	parts := strings.Split(repo, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return strings.ToLower(strings.Join(parts[len(parts)-2:], "/"))
+}
@@ -0,0 +1,270 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/urfave/cli"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestFork(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	context := func(args ...string) *cli.Context {
+		app := cli.NewApp()
+		app.Flags = []cli.Flag{
+			cli.StringFlag{Name: "api-key"},
+			cli.StringFlag{Name: "artifact-file", Value: DefaultArtifactFile},
+			cli.StringFlag{Name: "base-url"},
+			cli.IntFlag{Name: "issue-num"},
+			cli.StringFlag{Name: "key"},
+			cli.StringSliceFlag{Name: "labels-add"},
+			cli.StringFlag{Name: "repo-name"},
+			cli.StringFlag{Name: "repo-owner"},
+		}
+
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range app.Flags {
+			f.Apply(set)
+		}
+		set.Parse(args)
+
+		return cli.NewContext(app, set, nil)
+	}
+
+	g.Describe("fork PRs", func() {
+		var dir string
+
+		g.Before(func() {
+			dir, _ = ioutil.TempDir("", "github-comment")
+		})
+
+		g.After(func() {
+			os.RemoveAll(dir)
+		})
+
+		pl := Plugin{
+			BaseURL:    "http://server.com",
+			Message:    "test message",
+			IssueNum:   12,
+			Key:        "123",
+			RepoName:   "test-repo",
+			RepoOwner:  "test-org",
+			SourceRepo: "someone/test-repo",
+		}
+
+		g.It("detects forks", func() {
+			g.Assert(pl.IsFork()).IsTrue()
+
+			for _, source := range []string{"test-org/test-repo", "Test-Org/Test-Repo", "https://github.com/test-org/test-repo.git", "git@github.com:test-org/test-repo.git", ""} {
+				pl := pl
+				pl.SourceRepo = source
+				g.Assert(pl.IsFork()).IsFalse(source)
+			}
+
+			pl := pl
+			pl.IssueNum = 0
+			g.Assert(pl.IsFork()).IsFalse()
+		})
+
+		g.It("skips without credentials", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.ForkMode = "skip"
+			pl.Preflight = true
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("still requires credentials for PRs from the same repository", func() {
+			pl := pl
+			pl.ForkMode = "skip"
+			pl.SourceRepo = "test-org/test-repo"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("writes the comment to an artifact", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.ArtifactFile = filepath.Join(dir, "pending.json")
+			pl.ForkMode = "artifact"
+			pl.Template = "build {{ .Build }}"
+			pl.BuildNumber = 3
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+
+			pending, err := ReadPending(pl.ArtifactFile)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(pending.Message).Equal("build 3")
+			g.Assert(pending.Issue).Equal(12)
+			g.Assert(pending.Key).Equal("123")
+			g.Assert(pending.SourceRepo).Equal("someone/test-repo")
+		})
+
+		g.It("rejects a manifest in artifact mode", func() {
+			pl := pl
+			pl.ForkMode = "artifact"
+			pl.Manifest = []ManifestEntry{{Message: "test"}}
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("rejects an invalid fork mode", func() {
+			pl := pl
+			pl.ForkMode = "random"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+
+	g.Describe("post-pending", func() {
+		var dir string
+
+		g.Before(func() {
+			dir, _ = ioutil.TempDir("", "github-comment")
+			getenv = func(string) string { return "" }
+		})
+
+		g.After(func() {
+			os.RemoveAll(dir)
+			getenv = os.Getenv
+		})
+
+		write := func(name string, pending PendingComment) string {
+			pending.Version = 1
+			path := filepath.Join(dir, name)
+			data, _ := json.Marshal(pending)
+			ioutil.WriteFile(path, data, 0644)
+			return path
+		}
+
+		args := func(extra ...string) []string {
+			return append([]string{
+				"--api-key", "fake",
+				"--base-url", "http://server.com",
+				"--repo-owner", "test-org",
+				"--repo-name", "test-repo",
+			}, extra...)
+		}
+
+		g.It("posts only the message of pending comments", func() {
+			defer gock.Off()
+
+			path := write("ok.json", PendingComment{RepoOwner: "test-org", RepoName: "test-repo", Issue: 12, Key: "123", Update: true, Build: 999999, Fields: map[string]string{"a": "b"}, Message: "from a fork"})
+			defer os.Remove(path)
+
+			var body string
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Map(func(req *http.Request) *http.Request {
+					data, _ := ioutil.ReadAll(req.Body)
+					req.Body = ioutil.NopCloser(bytes.NewReader(data))
+					body = string(data)
+					return req
+				}).
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "12", "--key", "123", "--labels-add", "bug")...))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(strings.Contains(body, "from a fork")).IsTrue(body)
+			g.Assert(strings.Contains(body, "999999")).IsFalse(body)
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("requires the trusted issue number and checks the default key", func() {
+			defer gock.Off()
+
+			path := write("untrusted.json", PendingComment{RepoOwner: "test-org", RepoName: "test-repo", Issue: 99, Key: "security-scan", Message: "from a fork"})
+			defer os.Remove(path)
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--key", "security-scan")...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal(fmt.Sprintf("Refusing to post %s. You must provide the issue number to post pending comments", path))
+
+			err = PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "99")...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal(fmt.Sprintf("Refusing to post %s. Pending comment has key %q, not %q", path, "security-scan", defaultKey(Plugin{RepoOwner: "test-org", RepoName: "test-repo", IssueNum: 99})))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("uses the default key without a trusted key", func() {
+			defer gock.Off()
+
+			key := defaultKey(Plugin{RepoOwner: "test-org", RepoName: "test-repo", IssueNum: 12})
+			path := write("default.json", PendingComment{RepoOwner: "test-org", RepoName: "test-repo", Issue: 12, Key: key, Message: "from a fork"})
+			defer os.Remove(path)
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				BodyString("from a fork").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "12")...))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("refuses comments for another repository", func() {
+			defer gock.Off()
+
+			path := write("repo.json", PendingComment{RepoOwner: "someone", RepoName: "test-repo", Issue: 12, Key: "123", Message: "from a fork"})
+			defer os.Remove(path)
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "12", "--key", "123")...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal(fmt.Sprintf("Refusing to post %s. Pending comment is for someone/test-repo, not test-org/test-repo", path))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("refuses comments for another PR", func() {
+			path := write("issue.json", PendingComment{RepoOwner: "test-org", RepoName: "test-repo", Issue: 13, Key: "123", Message: "from a fork"})
+			defer os.Remove(path)
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "12", "--key", "123")...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("refuses comments with another key", func() {
+			path := write("key.json", PendingComment{RepoOwner: "test-org", RepoName: "test-repo", Issue: 12, Key: "other", Message: "from a fork"})
+			defer os.Remove(path)
+
+			err := PostPendingFromCLI(context(args("--artifact-file", path, "--issue-num", "12", "--key", "123")...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("errors without pending comments", func() {
+			err := PostPendingFromCLI(context(args("--artifact-file", filepath.Join(dir, "*.json"))...))
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	graphqlRequest struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables,omitempty"`
+	}
+
+	graphqlResponse struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+)
+
+// graphqlURL returns the GraphQL endpoint matching the REST base URL
+func (p Plugin) graphqlURL() string {
+	u := *p.gitClient.BaseURL
+
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path = u.Path + "graphql"
+	}
+
+	return u.String()
+}
+
+// graphql executes a GraphQL query, decoding the response data into v
+func (p Plugin) graphql(ctx context.Context, query string, vars map[string]interface{}, v interface{}) error {
+	if p.gitClient == nil {
+		return fmt.Errorf("graphql(): git client not initialized")
+	}
+
+	req, err := p.gitClient.NewRequest("POST", p.graphqlURL(), graphqlRequest{Query: query, Variables: vars})
+	if err != nil {
+		return err
+	}
+
+	resp := &graphqlResponse{}
+	if _, err := p.gitClient.Do(ctx, req, resp); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("GraphQL request failed. %s", resp.Errors[0].Message)
+	}
+
+	if v == nil || len(resp.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Data, v)
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/go-github/github"
+)
+
+var labelColorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// label adds and removes the configured labels on the issue
+func (p Plugin) label(ctx context.Context) error {
+	if len(p.LabelsAdd) > 0 {
+		if p.LabelColor != "" {
+			if err := p.createLabels(ctx, p.LabelsAdd); err != nil {
+				return err
+			}
+		}
+
+		if _, _, err := p.gitClient.Issues.AddLabelsToIssue(ctx, p.RepoOwner, p.RepoName, p.IssueNum, p.LabelsAdd); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range p.LabelsRemove {
+		_, err := p.gitClient.Issues.RemoveLabelForIssue(ctx, p.RepoOwner, p.RepoName, p.IssueNum, name)
+
+		// The label not being on the issue is what we want
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createLabels creates any missing repository labels with the configured color
+func (p Plugin) createLabels(ctx context.Context, names []string) error {
+	for _, name := range names {
+		_, _, err := p.gitClient.Issues.GetLabel(ctx, p.RepoOwner, p.RepoName, name)
+
+		if err == nil {
+			continue
+		}
+
+		if !isNotFound(err) {
+			return err
+		}
+
+		label := &github.Label{
+			Name:  github.String(name),
+			Color: github.String(p.LabelColor),
+		}
+
+		if _, _, err := p.gitClient.Issues.CreateLabel(ctx, p.RepoOwner, p.RepoName, label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(*github.ErrorResponse)
+	return ok && e.Response != nil && e.Response.StatusCode == http.StatusNotFound
+}
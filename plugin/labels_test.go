@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestLabels(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("labels", func() {
+		pl := Plugin{
+			BaseURL:      "http://server.com",
+			Message:      "test message",
+			IssueNum:     12,
+			LabelsAdd:    []string{"tests-failing"},
+			LabelsRemove: []string{"tests-passing"},
+			RepoName:     "test-repo",
+			RepoOwner:    "test-org",
+			Token:        "fake",
+		}
+
+		g.It("rejects an invalid color", func() {
+			pl := pl
+			pl.LabelColor = "red"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error for invalid color")
+		})
+
+		g.It("adds and removes labels alongside the comment", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/labels").
+				BodyString(`\["tests-failing"\]`).
+				Reply(200).
+				JSON([]map[string]string{{"name": "tests-failing"}})
+
+			gock.New("http://server.com").
+				Delete("repos/test-org/test-repo/issues/12/labels/tests-passing").
+				Reply(200)
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("ignores labels that are not present", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/labels").
+				Reply(200).
+				JSON([]map[string]string{{"name": "tests-failing"}})
+
+			gock.New("http://server.com").
+				Delete("repos/test-org/test-repo/issues/12/labels/tests-passing").
+				Reply(404).
+				JSON(map[string]string{"message": "Label does not exist"})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("creates missing labels with a color", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.LabelColor = "d73a4a"
+			pl.LabelsRemove = nil
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/labels/tests-failing").
+				Reply(404).
+				JSON(map[string]string{"message": "Not Found"})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/labels").
+				BodyString("d73a4a").
+				Reply(201).
+				JSON(map[string]string{"name": "tests-failing"})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/labels").
+				Reply(200).
+				JSON([]map[string]string{{"name": "tests-failing"}})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,280 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	modeCreate = "create"
+	modeUpdate = "update"
+	modeDelete = "delete"
+
+	policyFailFast  = "fail_fast"
+	policyFailAtEnd = "fail_at_end"
+	policyNever     = "never"
+)
+
+type (
+	// ManifestEntry is a single comment to post from a manifest
+	ManifestEntry struct {
+		Key         string `yaml:"key"`
+		Target      int    `yaml:"target"`
+		Message     string `yaml:"message"`
+		MessageFile string `yaml:"message_file"`
+		Template    string `yaml:"template"`
+		Mode        string `yaml:"mode"`
+	}
+
+	// Result is the outcome of a manifest entry
+	Result struct {
+		Key       string
+		Target    int
+		Action    string
+		CommentID int64
+		Err       error
+	}
+)
+
+// LoadManifest reads the comments of a YAML or JSON manifest file
+func LoadManifest(path string) ([]ManifestEntry, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read manifest %s. %s", path, err)
+	}
+
+	manifest := struct {
+		Comments []ManifestEntry `yaml:"comments"`
+	}{}
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, fmt.Errorf("Failed to parse manifest %s. %s", path, err)
+	}
+
+	for i, entry := range manifest.Comments {
+		if entry.MessageFile == "" {
+			continue
+		}
+
+		if entry.MessageFile == "-" {
+			return nil, fmt.Errorf("Invalid manifest %s. Manifest entry %d can not read message_file from stdin", path, i)
+		}
+
+		// message files are relative to the manifest, not the working directory
+		file := entry.MessageFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+
+		message, err := ReadMessage([]string{file}, "\n", nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Comments[i].Message = message
+	}
+
+	return manifest.Comments, nil
+}
+
+// ExecManifest posts every manifest entry, sharing the comment index between
+// entries. Errors are handled according to the manifest policy.
+func (p Plugin) ExecManifest() ([]Result, error) {
+	return p.ExecManifestContext(p.gitContext)
+}
+
+// ExecManifestContext posts every manifest entry like ExecManifest, cancelling
+// GitHub requests when ctx is done or the timeout expires
+func (p Plugin) ExecManifestContext(ctx context.Context) ([]Result, error) {
+	if p.gitClient == nil {
+		return nil, fmt.Errorf("ExecManifest(): git client not initialized")
+	}
+
+	parent := ctx
+	ctx, cancel := p.withTimeout(parent)
+	defer cancel()
+
+	p.gitContext = ctx
+	results, err := p.execManifest()
+
+	return results, p.maskError(p.timedOut(parent, ctx, apiError(err)))
+}
+
+func (p Plugin) execManifest() ([]Result, error) {
+	p.comments.invalidate()
+
+	var (
+		results []Result
+		failed  int
+		own     *ownership
+		err     error
+	)
+
+	if p.Codeowners {
+		own, err = p.ownership(p.gitContext)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range p.Manifest {
+		result := p.execEntry(entry, own)
+		result.Err = apiError(result.Err)
+		results = append(results, result)
+
+		log := p.log().WithFields(logrus.Fields{
+			"key":     result.Key,
+			"target":  result.Target,
+			"action":  result.Action,
+			"comment": result.CommentID,
+		})
+
+		if result.Err == nil {
+			log.Info("Manifest entry done")
+			continue
+		}
+
+		log.WithError(result.Err).Error("Manifest entry failed")
+		failed++
+
+		if p.ManifestPolicy == policyFailFast {
+			return results, fmt.Errorf("Manifest entry %q failed. %s", result.Key, result.Err)
+		}
+	}
+
+	if failed > 0 && p.ManifestPolicy != policyNever {
+		return results, fmt.Errorf("%d of %d manifest entries failed", failed, len(p.Manifest))
+	}
+
+	return results, nil
+}
+
+func (p Plugin) execEntry(entry ManifestEntry, own *ownership) Result {
+	ep := p
+	ep.Key = entry.Key
+	ep.Message = entry.Message
+	ep.Template = entry.Template
+	ep.Update = entry.Mode != modeCreate
+
+	if entry.Target != 0 {
+		ep.IssueNum = entry.Target
+	}
+
+	result := Result{Key: entry.Key, Target: ep.IssueNum, Action: entry.Mode}
+	if result.Action == "" {
+		result.Action = modeUpdate
+	}
+
+	// Entries can target other PRs/issues than the run, check each one
+	reason, err := ep.stateReason(ep.gitContext)
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if reason != "" {
+		ep.log().Infof("Skipped because %s", reason)
+		result.Action = "skipped"
+		return result
+	}
+
+	var (
+		comment *github.IssueComment
+		prev    *Metadata
+	)
+
+	if ep.Update {
+		comment, err = ep.findComment(ep.gitContext)
+
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		if comment != nil {
+			prev = ParseMetadata(comment.GetBody())
+			result.CommentID = comment.GetID()
+		}
+	}
+
+	if prev != nil && prev.Build > ep.BuildNumber && ep.BuildNumber != 0 {
+		result.Action = "skipped"
+		return result
+	}
+
+	if entry.Mode == modeDelete {
+		if comment == nil {
+			result.Action = "none"
+			return result
+		}
+
+		if _, result.Err = ep.gitClient.Issues.DeleteComment(ep.gitContext, ep.RepoOwner, ep.RepoName, int(comment.GetID())); result.Err == nil {
+			ep.comments.remove(ep.IssueNum, comment.GetID())
+		}
+		return result
+	}
+
+	message, err := ep.render(prev, own)
+
+	if err == nil {
+		message, err = ep.redact(message)
+	}
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if isEmpty(message) {
+		if ep.OnEmpty == "" || ep.OnEmpty == emptyFail {
+			result.Err = fmt.Errorf("Refusing to post an empty comment")
+			return result
+		}
+
+		result.Action = "none"
+		if ep.OnEmpty == emptyDelete && comment != nil {
+			result.Action = modeDelete
+		}
+
+		_, result.Err = ep.handleEmpty(comment)
+		return result
+	}
+
+	comment, result.Err = ep.post(comment, message)
+
+	if isLocked(result.Err) && ep.lockedPolicy() == stateSkip {
+		ep.log().Infof("Skipped because #%d is locked", ep.IssueNum)
+		result.Action = "skipped"
+		result.Err = nil
+		return result
+	}
+
+	result.CommentID = comment.GetID()
+
+	return result
+}
+
+func validateManifest(entries []ManifestEntry) error {
+	for i, entry := range entries {
+		switch entry.Mode {
+		case "", modeCreate, modeUpdate, modeDelete:
+		default:
+			return fmt.Errorf("Invalid mode %q for manifest entry %d, must be one of %s, %s or %s", entry.Mode, i, modeCreate, modeUpdate, modeDelete)
+		}
+
+		if entry.Key == "" && entry.Mode != modeCreate {
+			return fmt.Errorf("You must provide a key for manifest entry %d", i)
+		}
+	}
+
+	return nil
+}
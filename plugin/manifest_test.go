@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestManifest(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("LoadManifest", func() {
+		g.It("reads entries and message files", func() {
+			entries, err := LoadManifest("../testdata/manifest/manifest.yml")
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(len(entries)).Equal(4)
+			g.Assert(entries[1].Message).Equal("Service B failed\n")
+			g.Assert(entries[3].Target).Equal(13)
+		})
+
+		g.It("rejects message files from stdin", func() {
+			_, err := LoadManifest("../testdata/manifest/stdin.yml")
+
+			g.Assert(err != nil).IsTrue("should have received error for stdin")
+			g.Assert(err.Error()).Equal("Invalid manifest ../testdata/manifest/stdin.yml. Manifest entry 0 can not read message_file from stdin")
+		})
+	})
+
+	g.Describe("ExecManifest", func() {
+		entries, err := LoadManifest("../testdata/manifest/manifest.yml")
+		if err != nil {
+			g.Fail("Failed to load manifest for testing")
+		}
+
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			IssueNum:  12,
+			Manifest:  entries,
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		g.It("rejects entries without a key", func() {
+			pl := pl
+			pl.Manifest = []ManifestEntry{{Message: "no key"}}
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error for missing key")
+		})
+
+		g.It("posts every entry listing comments once", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				BodyString("Web service passed").
+				Reply(200).
+				JSON(map[string]interface{}{"id": 7})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				BodyString("Service B failed").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 20})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/13/comments").
+				BodyString("Deployed to staging").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 21})
+
+			results, err := p.ExecManifest()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+			g.Assert(len(results)).Equal(4)
+			g.Assert(results[1].CommentID).Equal(int64(20))
+			g.Assert(results[2].Action).Equal("none")
+		})
+
+		g.It("applies on_empty to empty entries", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnEmpty = "delete"
+			pl.Manifest = []ManifestEntry{{Key: "123", Message: " "}}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Delete("repos/test-org/test-repo/issues/comments/7").
+				Reply(204)
+
+			results, err := p.ExecManifest()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+			g.Assert(results[0].Action).Equal("delete")
+		})
+
+		g.It("skips entries on locked conversations by default", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Manifest = []ManifestEntry{{Message: "Deployed to staging", Mode: "create", Target: 13}}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/13/comments").
+				Reply(422).
+				JSON(map[string]interface{}{
+					"message": "Validation Failed",
+					"errors":  []map[string]interface{}{{"resource": "IssueComment", "code": "unprocessable", "message": "Unable to create comment because issue is locked."}},
+				})
+
+			results, err := p.ExecManifest()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(results[0].Action).Equal("skipped")
+		})
+
+		g.It("applies the state policies to the target of entries", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnClosed = "skip"
+			pl.Manifest = []ManifestEntry{{Message: "Deployed to staging", Mode: "create", Target: 13}}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/13").
+				Reply(200).
+				JSON(map[string]interface{}{"number": 13, "state": "closed"})
+
+			results, err := p.ExecManifest()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+			g.Assert(results[0].Action).Equal("skipped")
+		})
+
+		g.It("continues after failures and fails at the end", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Manifest = []ManifestEntry{
+				{Message: "first", Mode: "create"},
+				{Message: "second", Mode: "create"},
+			}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				BodyString("first").
+				Reply(500).
+				JSON(map[string]string{"message": "Server Error"})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				BodyString("second").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 21})
+
+			results, err := p.ExecManifest()
+
+			g.Assert(err != nil).IsTrue("should have received error for failed entry")
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(results[0].Err != nil).IsTrue()
+			g.Assert(results[1].Err == nil).IsTrue()
+		})
+	})
+}
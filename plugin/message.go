@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// ReadMessage reads and concatenates the files matching patterns, joined by
+// separator. Matches of a glob are read in lexical order and `-` reads stdin.
+// It is an error for a pattern to match no files.
+func ReadMessage(patterns []string, separator string, stdin io.Reader) (string, error) {
+	var parts []string
+
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			data, err := ioutil.ReadAll(stdin)
+
+			if err != nil {
+				return "", fmt.Errorf("Failed to read message from stdin. %s", err)
+			}
+
+			parts = append(parts, string(data))
+			continue
+		}
+
+		paths, err := filepath.Glob(pattern)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid message file pattern %s. %s", pattern, err)
+		}
+
+		if len(paths) == 0 {
+			return "", fmt.Errorf("Message file %s does not exist", pattern)
+		}
+
+		sort.Strings(paths)
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+
+			if err != nil {
+				return "", fmt.Errorf("Failed to read message file %s. %s", path, err)
+			}
+
+			parts = append(parts, string(data))
+		}
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+// messageFromCLI returns the message option or the message files, wrapped in
+// the header and footer files
+func messageFromCLI(c *cli.Context) (string, error) {
+	separator := c.String("message-separator")
+	message := c.String("message")
+
+	if message == "" && len(c.StringSlice("message-file")) > 0 {
+		var err error
+		message, err = ReadMessage(c.StringSlice("message-file"), separator, os.Stdin)
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Don't wrap an empty message so it is still recognized as empty
+	if strings.TrimSpace(message) == "" {
+		return message, nil
+	}
+
+	parts := []string{message}
+
+	if header := c.String("message-header-file"); header != "" {
+		data, err := ReadMessage([]string{header}, separator, os.Stdin)
+
+		if err != nil {
+			return "", err
+		}
+
+		parts = append([]string{data}, parts...)
+	}
+
+	if footer := c.String("message-footer-file"); footer != "" {
+		data, err := ReadMessage([]string{footer}, separator, os.Stdin)
+
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, data)
+	}
+
+	return strings.Join(parts, separator), nil
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/urfave/cli"
+)
+
+func TestMessage(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ReadMessage", func() {
+		g.It("concatenates globs in order", func() {
+			message, err := ReadMessage([]string{"../testdata/message/*.md"}, "---\n", nil)
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("Service A passed\n---\nService B failed\n")
+		})
+
+		g.It("reads stdin for -", func() {
+			message, err := ReadMessage([]string{"-", "../testdata/message/b.md"}, "\n", strings.NewReader("From stdin"))
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("From stdin\nService B failed\n")
+		})
+
+		g.It("errors with the path of missing files", func() {
+			_, err := ReadMessage([]string{"../testdata/message/missing.md"}, "\n", nil)
+
+			g.Assert(err != nil).IsTrue("should have received error for missing file")
+			g.Assert(strings.Contains(err.Error(), "missing.md")).IsTrue(err.Error())
+		})
+	})
+
+	g.Describe("messageFromCLI", func() {
+		context := func(args ...string) *cli.Context {
+			app := cli.NewApp()
+			app.Flags = []cli.Flag{
+				cli.StringFlag{Name: "message"},
+				cli.StringSliceFlag{Name: "message-file"},
+				cli.StringFlag{Name: "message-separator", Value: "\n"},
+				cli.StringFlag{Name: "message-header-file"},
+				cli.StringFlag{Name: "message-footer-file"},
+			}
+
+			set := flag.NewFlagSet("test", flag.ContinueOnError)
+			for _, f := range app.Flags {
+				f.Apply(set)
+			}
+			set.Parse(args)
+
+			return cli.NewContext(app, set, nil)
+		}
+
+		g.It("wraps the message in the header and footer", func() {
+			message, err := messageFromCLI(context("--message-file", "../testdata/message/b.md", "--message-header-file", "../testdata/message/a.md", "--message-footer-file", "../testdata/message/a.md", "--message-separator", "---\n"))
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("Service A passed\n---\nService B failed\n---\nService A passed\n")
+		})
+
+		g.It("does not wrap an empty message", func() {
+			message, err := messageFromCLI(context("--message-header-file", "../testdata/message/a.md"))
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("")
+		})
+
+		g.It("prefers message over message files", func() {
+			message, err := messageFromCLI(context("--message", "inline", "--message-file", "../testdata/message/missing.md"))
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(message).Equal("inline")
+		})
+
+		g.It("errors when a message file does not exist", func() {
+			_, err := messageFromCLI(context("--message-file", "../testdata/message/missing.md"))
+
+			g.Assert(err != nil).IsTrue("should have received error for missing file")
+			g.Assert(err.Error()).Equal("Message file ../testdata/message/missing.md does not exist")
+		})
+
+		g.It("errors when the header file does not exist", func() {
+			_, err := messageFromCLI(context("--message", "inline", "--message-header-file", "../testdata/message/missing.md"))
+
+			g.Assert(err != nil).IsTrue("should have received error for missing header")
+			g.Assert(strings.Contains(err.Error(), "missing.md")).IsTrue(err.Error())
+		})
+	})
+}
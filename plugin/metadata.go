@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataVersion is the version of the marker written by this plugin
+const metadataVersion = 1
+
+var (
+	metadataPattern = regexp.MustCompile(`<!-- github-comment: v(\d+) ([A-Za-z0-9+/=]+) -->`)
+	legacyPattern   = regexp.MustCompile(`<!-- id: (.+?) -->`)
+
+	// now is overridden in tests to get stable timestamps
+	now = time.Now
+)
+
+type (
+	// Metadata is the state stored in the hidden marker of a keyed comment
+	Metadata struct {
+		Version   int               `json:"v"`
+		Key       string            `json:"key"`
+		Build     int               `json:"build,omitempty"`
+		Commit    string            `json:"sha,omitempty"`
+		Timestamp time.Time         `json:"ts"`
+		Resolved  bool              `json:"resolved,omitempty"`
+		Minimized bool              `json:"minimized,omitempty"`
+		Fields    map[string]string `json:"fields,omitempty"`
+	}
+)
+
+// ParseMetadata returns the metadata stored in a comment body, nil if none exist.
+// Legacy `<!-- id: KEY -->` markers are returned with only the Key set. The
+// marker is appended, so the last one wins over markers quoted in the message.
+func ParseMetadata(body string) *Metadata {
+	if m := lastSubmatch(metadataPattern, body); m != nil {
+		version, _ := strconv.Atoi(m[1])
+
+		data, err := base64.StdEncoding.DecodeString(m[2])
+		if err != nil {
+			return nil
+		}
+
+		md := &Metadata{}
+		if err := json.Unmarshal(data, md); err != nil {
+			return nil
+		}
+		md.Version = version
+
+		return md
+	}
+
+	if m := lastSubmatch(legacyPattern, body); m != nil {
+		return &Metadata{Key: m[1]}
+	}
+
+	return nil
+}
+
+// Marker returns the hidden HTML comment to append to a comment body
+func (m Metadata) Marker() (string, error) {
+	m.Version = metadataVersion
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode comment metadata. %s", err)
+	}
+
+	return fmt.Sprintf("<!-- github-comment: v%d %s -->", m.Version, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// replaceMarker returns body with its marker replaced by the marker of md,
+// appending one if none exist
+func replaceMarker(body string, md Metadata) (string, error) {
+	marker, err := md.Marker()
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, pattern := range []*regexp.Regexp{metadataPattern, legacyPattern} {
+		if loc := lastIndex(pattern, body); loc != nil {
+			return body[:loc[0]] + marker + body[loc[1]:], nil
+		}
+	}
+
+	return fmt.Sprintf("%s\n%s\n", body, marker), nil
+}
+
+// lastIndex returns the location of the last match of pattern in s
+func lastIndex(pattern *regexp.Regexp, s string) []int {
+	matches := pattern.FindAllStringIndex(s, -1)
+
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return matches[len(matches)-1]
+}
+
+// lastSubmatch returns the submatches of the last match of pattern in s
+func lastSubmatch(pattern *regexp.Regexp, s string) []string {
+	matches := pattern.FindAllStringSubmatch(s, -1)
+
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return matches[len(matches)-1]
+}
+
+// metadata builds the metadata for the comment posted by this run
+func (p Plugin) metadata() Metadata {
+	return Metadata{
+		Key:       p.Key,
+		Build:     p.BuildNumber,
+		Commit:    p.CommitSHA,
+		Timestamp: now().UTC(),
+		Fields:    p.Metadata,
+	}
+}
+
+// parseFields turns a list of KEY=VALUE pairs into a map
+func parseFields(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	fields := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid metadata field %q, expected KEY=VALUE", pair)
+		}
+		fields[parts[0]] = parts[1]
+	}
+
+	return fields, nil
+}
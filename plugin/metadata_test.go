@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestMetadata(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	now = func() time.Time {
+		return time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	g.Describe("ParseMetadata", func() {
+		g.It("parses legacy markers", func() {
+			md := ParseMetadata("Me too\n<!-- id: 123 -->\n")
+
+			g.Assert(md != nil).IsTrue("should have found metadata")
+			g.Assert(md.Version).Equal(0)
+			g.Assert(md.Key).Equal("123")
+		})
+
+		g.It("parses legacy markers with spaces in the key", func() {
+			md := ParseMetadata("Coverage dropped\n<!-- id: coverage report -->\n")
+
+			g.Assert(md != nil).IsTrue("should have found metadata")
+			g.Assert(md.Key).Equal("coverage report")
+		})
+
+		g.It("round trips versioned markers", func() {
+			marker, err := Metadata{
+				Key:    "123",
+				Build:  4,
+				Commit: "abc123",
+				Fields: map[string]string{"coverage": "87%"},
+			}.Marker()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			md := ParseMetadata("Me too\n" + marker + "\n")
+
+			g.Assert(md != nil).IsTrue("should have found metadata")
+			g.Assert(md.Version).Equal(metadataVersion)
+			g.Assert(md.Key).Equal("123")
+			g.Assert(md.Build).Equal(4)
+			g.Assert(md.Commit).Equal("abc123")
+			g.Assert(md.Fields["coverage"]).Equal("87%")
+		})
+
+		g.It("uses the last marker when the message quotes one", func() {
+			quoted, _ := Metadata{Key: "other", Build: 99}.Marker()
+			marker, _ := Metadata{Key: "123", Build: 4}.Marker()
+
+			md := ParseMetadata("Copied from #3:\n> " + quoted + "\n" + marker + "\n")
+
+			g.Assert(md != nil).IsTrue("should have found metadata")
+			g.Assert(md.Key).Equal("123")
+			g.Assert(md.Build).Equal(4)
+
+			md = ParseMetadata("See `<!-- id: other -->`\n<!-- id: 123 -->\n")
+
+			g.Assert(md != nil).IsTrue("should have found metadata")
+			g.Assert(md.Key).Equal("123")
+		})
+
+		g.It("returns nil without a marker", func() {
+			g.Assert(ParseMetadata("Me too") == nil).IsTrue()
+		})
+	})
+
+	g.Describe("parseFields", func() {
+		g.It("parses KEY=VALUE pairs", func() {
+			fields, err := parseFields([]string{"a=1", "b=x=y"})
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(fields["a"]).Equal("1")
+			g.Assert(fields["b"]).Equal("x=y")
+		})
+
+		g.It("rejects malformed pairs", func() {
+			_, err := parseFields([]string{"nope"})
+
+			g.Assert(err != nil).IsTrue("should have received error for malformed pair")
+		})
+	})
+
+	g.Describe("comparison with previous run", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Update:    true,
+			Token:     "fake",
+		}
+
+		g.It("skips updates from older builds", func() {
+			defer gock.Off()
+
+			pl.BuildNumber = 9
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment-metadata.json")
+
+			// We do not expect this endpoint to get called
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsFalse()
+		})
+
+		g.It("exposes previous metadata to templates", func() {
+			defer gock.Off()
+
+			pl.BuildNumber = 11
+			pl.Template = "{{ .Message }} (was {{ .Previous.Fields.coverage }} in #{{ .Previous.Build }})"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment-metadata.json")
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				BodyString(`test message \(was 87% in #10\)`).
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+type (
+	// Option configures how the plugin talks to GitHub when embedding it
+	Option func(*options)
+
+	options struct {
+		httpClient *http.Client
+		transport  http.RoundTripper
+		userAgent  string
+		gitClient  *github.Client
+		logger     logrus.FieldLogger
+	}
+)
+
+// WithHTTPClient uses a copy of client for GitHub requests, adding the
+// credentials to its transport. The request timeout applies if client has none.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport sends GitHub requests through transport, e.g. for proxies or
+// instrumentation. The credentials are added before it.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header of GitHub requests
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithGitHubClient uses a pre-built client as is. Credentials and base URL of
+// the plugin are ignored, the client is expected to carry its own.
+func WithGitHubClient(client *github.Client) Option {
+	return func(o *options) {
+		o.gitClient = client
+	}
+}
+
+// WithLogger logs to logger instead of the standard logrus logger
+func WithLogger(logger logrus.FieldLogger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// log returns the logger of the plugin
+func (p Plugin) log() logrus.FieldLogger {
+	if p.logger == nil {
+		return logrus.StandardLogger()
+	}
+
+	return p.logger
+}
+
+// httpClient returns the client for GitHub requests, authenticating with the
+// token or username and password of the plugin
+func (p Plugin) httpClient(o options) (*http.Client, error) {
+	client := &http.Client{}
+	if o.httpClient != nil {
+		c := *o.httpClient
+		client = &c
+	}
+
+	if client.Timeout == 0 {
+		client.Timeout = p.requestTimeout()
+	}
+
+	// nil uses http.DefaultTransport
+	base := client.Transport
+	if o.transport != nil {
+		base = o.transport
+	}
+
+	if p.customTransport() {
+		if base != nil {
+			return nil, fmt.Errorf("TLS and proxy settings can not be combined with a custom transport")
+		}
+
+		t, err := p.transport()
+
+		if err != nil {
+			return nil, err
+		}
+		base = t
+	}
+
+	if p.Token != "" {
+		client.Transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: p.Token}),
+			Base:   base,
+		}
+	} else {
+		client.Transport = &github.BasicAuthTransport{
+			Username:  strings.TrimSpace(p.Username),
+			Password:  strings.TrimSpace(p.Password),
+			Transport: base,
+		}
+	}
+
+	return client, nil
+}
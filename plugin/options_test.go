@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/franela/goblin"
+	"github.com/google/go-github/github"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// countingTransport counts requests, sending them through the default transport
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOptions(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("options", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		g.It("sends requests through the transport with credentials", func() {
+			defer gock.Off()
+
+			transport := &countingTransport{}
+
+			p, err := NewFromPlugin(pl, WithTransport(transport), WithUserAgent("my-tool/1.0"))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				MatchHeader("Authorization", "^Bearer fake$").
+				MatchHeader("User-Agent", "^my-tool/1.0$").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(transport.count).Equal(1)
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("adds credentials to a custom http client", func() {
+			defer gock.Off()
+
+			transport := &countingTransport{}
+			client := &http.Client{Transport: transport}
+
+			pl := pl
+			pl.Token = ""
+			pl.Username = "user"
+			pl.Password = "pass"
+
+			p, err := NewFromPlugin(pl, WithHTTPClient(client))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				MatchHeader("Authorization", "^Basic ").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(transport.count).Equal(1)
+			g.Assert(client.Transport == transport).IsTrue("Expected the client to be left unchanged")
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("uses a pre-built client without credentials", func() {
+			defer gock.Off()
+
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse("http://other.com/")
+
+			pl := pl
+			pl.Token = ""
+
+			p, err := NewFromPlugin(pl, WithGitHubClient(client))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://other.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("logs to the logger", func() {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.Out = &buf
+
+			pl := pl
+			pl.BuildStatus = "success"
+			pl.OnStatus = []string{"failure"}
+
+			p, err := NewFromPlugin(pl, WithLogger(logger))
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(strings.Contains(buf.String(), "Skipped because")).IsTrue(fmt.Sprintf("Logged: %s", buf.String()))
+		})
+	})
+}
@@ -4,60 +4,225 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"net/url"
-	"strings"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
 	"github.com/urfave/cli"
-	"golang.org/x/oauth2"
 )
 
 type (
 	Plugin struct {
-		BaseURL   string
-		IssueNum  int
-		Key       string
-		Message   string
-		Password  string
-		RepoName  string
-		RepoOwner string
-		Update    bool
-		Username  string
-		Token     string
-
+		Annotations       []Annotation
+		ArtifactFile      string
+		BaseURL           string
+		BuildLink         string
+		BuildNumber       int
+		BuildStatus       string
+		CACert            string
+		ClientCert        string
+		ClientKey         string
+		Codeowners        bool
+		CommitSHA         string
+		CredentialCommand string
+		ForkMode          string
+		IssueNum          int
+		Key               string
+		LabelColor        string
+		LabelsAdd         []string
+		LabelsRemove      []string
+		Manifest          []ManifestEntry
+		ManifestPolicy    string
+		MaxMentions       int
+		Message           string
+		Metadata          map[string]string
+		NetrcFile         string
+		OnClosed          string
+		OnEmpty           string
+		OnLocked          string
+		OnMerged          string
+		OnRedact          string
+		OnStatus          []string
+		Password          string
+		Paths             []string
+		PathsIgnore       []string
+		Preflight         bool
+		PrevBuildStatus   string
+		Proxy             string
+		ReactionTarget    string
+		Reactions         []string
+		ReactionsRemove   []string
+		RedactEnv         []string
+		RedactPatterns    []string
+		RepoLink          string
+		RepoName          string
+		RepoOwner         string
+		RequestReviews    bool
+		RequestTimeout    time.Duration
+		Resolve           string
+		ResolveMessage    string
+		Search            string
+		Since             time.Time
+		SkipComment       bool
+		SkipVerify        bool
+		SourceRepo        string
+		StatusContext     string
+		StatusTarget      string
+		StatusTitle       string
+		Template          string
+		Update            bool
+		Username          string
+		Token             string
+		TokenFile         string
+		Timeout           time.Duration
+		TriggerComment    int64
+
+		comments   *commentIndex
 		gitClient  *github.Client
 		gitContext context.Context
+		logger     logrus.FieldLogger
 	}
 )
 
-func NewFromCLI(c *cli.Context) (*Plugin, error) {
-	p := Plugin{
-		BaseURL:   c.String("base-url"),
-		Key:       c.String("key"),
-		Message:   c.String("message"),
-		IssueNum:  c.Int("issue-num"),
-		Password:  c.String("password"),
-		RepoName:  c.String("repo-name"),
-		RepoOwner: c.String("repo-owner"),
-		Token:     c.String("api-key"),
-		Update:    c.Bool("update"),
-		Username:  c.String("username"),
+func NewFromCLI(c *cli.Context, opts ...Option) (*Plugin, error) {
+	p, err := pluginFromCLI(c)
+
+	if err != nil {
+		return nil, err
 	}
 
-	err := p.init()
+	err = p.init(opts...)
 
 	if err != nil {
-		return nil, err
+		return nil, p.maskError(err)
 	}
 
 	return &p, nil
 }
 
-func NewFromPlugin(p Plugin) (*Plugin, error) {
-	err := p.init()
+// pluginFromCLI returns the plugin settings of the command line, environment
+// and config file
+func pluginFromCLI(c *cli.Context) (Plugin, error) {
+	if err := applyEnv(c, DetectEnv(getenv)); err != nil {
+		return Plugin{}, err
+	}
+
+	if err := applyConfig(c); err != nil {
+		return Plugin{}, err
+	}
+
+	fields, err := parseFields(c.StringSlice("metadata"))
 
 	if err != nil {
-		return nil, err
+		return Plugin{}, err
+	}
+
+	annotations, err := readAnnotations(c.String("status-annotations-file"))
+
+	if err != nil {
+		return Plugin{}, err
+	}
+
+	message, err := messageFromCLI(c)
+
+	if err != nil {
+		return Plugin{}, err
+	}
+
+	since, err := parseSince(c.String("since"))
+
+	if err != nil {
+		return Plugin{}, err
+	}
+
+	netrc := c.String("netrc-file")
+	if netrc == "" {
+		netrc = DefaultNetrcFile()
+	}
+
+	var manifest []ManifestEntry
+	if path := c.String("manifest"); path != "" {
+		manifest, err = LoadManifest(path)
+
+		if err != nil {
+			return Plugin{}, err
+		}
+	}
+
+	p := Plugin{
+		Annotations:       annotations,
+		ArtifactFile:      c.String("artifact-file"),
+		BaseURL:           c.String("base-url"),
+		BuildLink:         c.String("build-link"),
+		BuildNumber:       c.Int("build-number"),
+		BuildStatus:       c.String("build-status"),
+		CACert:            c.String("ca-cert"),
+		ClientCert:        c.String("client-cert"),
+		ClientKey:         c.String("client-key"),
+		Codeowners:        c.Bool("codeowners"),
+		CommitSHA:         c.String("commit-sha"),
+		CredentialCommand: c.String("credential-command"),
+		ForkMode:          c.String("fork-mode"),
+		Key:               c.String("key"),
+		LabelColor:        c.String("label-color"),
+		LabelsAdd:         c.StringSlice("labels-add"),
+		LabelsRemove:      c.StringSlice("labels-remove"),
+		Manifest:          manifest,
+		ManifestPolicy:    c.String("manifest-policy"),
+		MaxMentions:       c.Int("max-mentions"),
+		Message:           message,
+		Metadata:          fields,
+		NetrcFile:         netrc,
+		IssueNum:          c.Int("issue-num"),
+		OnClosed:          c.String("on-closed"),
+		OnEmpty:           c.String("on-empty"),
+		OnLocked:          c.String("on-locked"),
+		OnMerged:          c.String("on-merged"),
+		OnRedact:          c.String("on-redact"),
+		OnStatus:          c.StringSlice("on-status"),
+		Password:          c.String("password"),
+		Paths:             c.StringSlice("paths"),
+		PathsIgnore:       c.StringSlice("paths-ignore"),
+		Preflight:         c.Bool("preflight"),
+		PrevBuildStatus:   c.String("prev-build-status"),
+		Proxy:             c.String("proxy"),
+		ReactionTarget:    c.String("reaction-target"),
+		Reactions:         c.StringSlice("reactions"),
+		ReactionsRemove:   c.StringSlice("reactions-remove"),
+		RedactEnv:         c.StringSlice("redact-env"),
+		RedactPatterns:    c.StringSlice("redact-patterns"),
+		RepoLink:          c.String("repo-link"),
+		RepoName:          c.String("repo-name"),
+		RepoOwner:         c.String("repo-owner"),
+		RequestReviews:    c.Bool("request-reviews"),
+		RequestTimeout:    c.Duration("request-timeout"),
+		Resolve:           c.String("resolve"),
+		ResolveMessage:    c.String("resolve-message"),
+		Search:            c.String("search"),
+		Since:             since,
+		SkipComment:       c.Bool("skip-comment"),
+		SkipVerify:        c.Bool("skip-verify"),
+		SourceRepo:        c.String("source-repo"),
+		StatusContext:     c.String("status-context"),
+		StatusTarget:      c.String("status"),
+		StatusTitle:       c.String("status-title"),
+		Template:          c.String("template"),
+		Timeout:           c.Duration("timeout"),
+		Token:             c.String("api-key"),
+		TokenFile:         c.String("api-key-file"),
+		TriggerComment:    c.Int64("trigger-comment"),
+		Update:            c.Bool("update"),
+		Username:          c.String("username"),
+	}
+
+	return p, nil
+}
+
+func NewFromPlugin(p Plugin, opts ...Option) (*Plugin, error) {
+	err := p.init(opts...)
+
+	if err != nil {
+		return nil, p.maskError(err)
 	}
 
 	return &p, nil
@@ -65,49 +230,257 @@ func NewFromPlugin(p Plugin) (*Plugin, error) {
 
 // Exec executes the plugin
 func (p Plugin) Exec() error {
+	return p.ExecContext(p.gitContext)
+}
+
+// ExecContext executes the plugin, cancelling GitHub requests when ctx is done
+// or the timeout expires
+func (p Plugin) ExecContext(ctx context.Context) error {
 	if p.gitClient == nil {
 		return fmt.Errorf("Exec(): git client not initialized")
 	}
 
-	ic := &github.IssueComment{
-		Body: &p.Message,
+	parent := ctx
+	ctx, cancel := p.withTimeout(parent)
+	defer cancel()
+
+	p.gitContext = ctx
+	return p.maskError(p.timedOut(parent, ctx, apiError(p.exec())))
+}
+
+func (p Plugin) exec() error {
+	if p.forkSafe() {
+		return p.execFork()
 	}
 
-	var err error
-	if p.Update {
-		// Append plugin comment ID to comment message so we can search for it later
-		message := fmt.Sprintf("%s\n<!-- id: %s -->\n", p.Message, p.Key)
-		ic.Body = &message
+	p.comments.invalidate()
+
+	if p.resolving() {
+		if err := p.resolve(); err != nil {
+			return err
+		}
+
+		if err := p.label(p.gitContext); err != nil {
+			return err
+		}
+
+		return p.react(nil)
+	}
+
+	reason, err := p.skipReason()
+
+	if err != nil {
+		return err
+	}
 
-		comment, err := p.Comment()
+	if reason == "" {
+		reason, err = p.stateReason(p.gitContext)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if reason != "" {
+		p.log().Infof("Skipped because %s", reason)
+		return nil
+	}
+
+	if len(p.Manifest) > 0 {
+		if _, err := p.execManifest(); err != nil {
+			return err
+		}
+
+		if err := p.label(p.gitContext); err != nil {
+			return err
+		}
+
+		return p.react(nil)
+	}
+
+	var (
+		comment *github.IssueComment
+		prev    *Metadata
+		own     *ownership
+	)
+
+	if p.Codeowners {
+		own, err = p.ownership(p.gitContext)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.Update {
+		comment, err = p.findComment(p.gitContext)
 
 		if err != nil {
 			return err
 		}
 
 		if comment != nil {
-			_, _, err = p.gitClient.Issues.EditComment(p.gitContext, p.RepoOwner, p.RepoName, int(*comment.ID), ic)
+			prev = ParseMetadata(comment.GetBody())
+		}
+	}
+
+	message, err := p.render(prev, own)
+
+	if err != nil {
+		return err
+	}
+
+	// Redact once, the message is posted as the comment and the status
+	message, err = p.redact(message)
+
+	if err != nil {
+		return err
+	}
+
+	empty := isEmpty(message) && p.needsMessage()
+
+	if empty && (p.OnEmpty == "" || p.OnEmpty == emptyFail) {
+		return fmt.Errorf("Refusing to post an empty comment")
+	}
+
+	// Don't let a late finishing build overwrite results of a newer one
+	if prev != nil && prev.Build > p.BuildNumber && p.BuildNumber != 0 {
+		p.log().WithFields(logrus.Fields{
+			"build":    p.BuildNumber,
+			"previous": prev.Build,
+		}).Info("Skipping update, comment was written by a newer build")
+	} else if empty {
+		comment, err = p.handleEmpty(comment)
+
+		if err != nil {
+			return err
+		}
+	} else if !p.SkipComment {
+		// A minimized comment stays hidden when edited, show the new failure
+		if comment != nil && prev != nil && prev.Minimized {
+			if err := p.minimizeComment(p.gitContext, comment.GetID(), unminimizeMutation); err != nil {
+				return err
+			}
+		}
+
+		comment, err = p.post(comment, message)
+
+		if isLocked(err) && p.lockedPolicy() == stateSkip {
+			p.log().Infof("Skipped because #%d is locked", p.IssueNum)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := p.label(p.gitContext); err != nil {
+		return err
+	}
+
+	if p.RequestReviews {
+		if err := p.requestReviews(p.gitContext, own); err != nil {
+			return err
+		}
+	}
+
+	if p.StatusTarget != "" && !empty {
+		if err := p.publishStatus(p.gitContext, message); err != nil {
 			return err
 		}
 	}
 
-	_, _, err = p.gitClient.Issues.CreateComment(p.gitContext, p.RepoOwner, p.RepoName, p.IssueNum, ic)
-	return err
+	return p.react(comment)
+}
+
+// post creates the comment or updates the existing keyed comment, returning the
+// result. The message must already be redacted.
+func (p Plugin) post(comment *github.IssueComment, message string) (*github.IssueComment, error) {
+	var err error
+
+	ic := &github.IssueComment{
+		Body: &message,
+	}
+
+	if p.Update {
+		// Append plugin comment metadata to comment message so we can search for it later
+		marker, err := p.metadata().Marker()
+
+		if err != nil {
+			return nil, err
+		}
+
+		body := fmt.Sprintf("%s\n%s\n", message, marker)
+		ic.Body = &body
+
+		if comment != nil {
+			comment, _, err = p.gitClient.Issues.EditComment(p.gitContext, p.RepoOwner, p.RepoName, int(*comment.ID), ic)
+
+			if err != nil {
+				return nil, err
+			}
+
+			p.comments.replace(p.IssueNum, comment)
+			return comment, nil
+		}
+	}
+
+	comment, _, err = p.gitClient.Issues.CreateComment(p.gitContext, p.RepoOwner, p.RepoName, p.IssueNum, ic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.comments.add(p.IssueNum, comment)
+	return comment, nil
 }
 
-func (p *Plugin) init() error {
+func (p *Plugin) init(opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.logger != nil {
+		p.logger = o.logger
+	}
+
+	if o.gitClient != nil {
+		p.gitClient = o.gitClient
+	}
+
+	// Untrusted fork PR builds never read credentials
+	if !p.forkSafe() {
+		if err := p.loadCredentials(); err != nil {
+			return err
+		}
+	}
+	p.maskLogs()
+
 	err := p.validate()
 
 	if err != nil {
 		return err
 	}
 
-	err = p.initGitClient()
+	err = p.initGitClient(o)
 
 	if err != nil {
 		return err
 	}
 
+	p.comments = newCommentIndex()
+
+	if p.Preflight && !p.forkSafe() {
+		ctx, cancel := p.withTimeout(p.gitContext)
+		defer cancel()
+
+		if err := p.preflight(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Generate default plugin key if not specified
 	if p.Key == "" {
 		p.Key = defaultKey(*p)
@@ -116,68 +489,68 @@ func (p *Plugin) init() error {
 	return nil
 }
 
-func (p *Plugin) initGitClient() error {
-	if !strings.HasSuffix(p.BaseURL, "/") {
-		p.BaseURL = p.BaseURL + "/"
+func (p *Plugin) initGitClient(o options) error {
+	p.gitContext = context.Background()
+
+	if o.gitClient != nil {
+		if o.userAgent != "" {
+			p.gitClient.UserAgent = o.userAgent
+		}
+		return nil
 	}
 
-	baseURL, err := url.Parse(p.BaseURL)
+	baseURL, uploadURL, err := endpoints(p.baseURL())
 
 	if err != nil {
-		return fmt.Errorf("Failed to parse base URL. %s", err)
+		return err
 	}
+	p.BaseURL = baseURL.String()
 
-	p.gitContext = context.Background()
+	client, err := p.httpClient(o)
 
-	if p.Token != "" {
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: p.Token})
-		tc := oauth2.NewClient(p.gitContext, ts)
-		p.gitClient = github.NewClient(tc)
-	} else {
-		tp := github.BasicAuthTransport{
-			Username: strings.TrimSpace(p.Username),
-			Password: strings.TrimSpace(p.Password),
-		}
-		p.gitClient = github.NewClient(tp.Client())
+	if err != nil {
+		return err
 	}
+
+	p.gitClient = github.NewClient(client)
 	p.gitClient.BaseURL = baseURL
+	p.gitClient.UploadURL = uploadURL
+	if o.userAgent != "" {
+		p.gitClient.UserAgent = o.userAgent
+	}
 
 	return nil
 }
 
-// Comment returns existing comment, nil if none exist
+// Comment returns existing comment, nil if none exist. Comments are listed
+// once per run unless searching newest first.
 func (p Plugin) Comment() (*github.IssueComment, error) {
-	comments, err := p.allIssueComments(p.gitContext)
+	return p.CommentContext(p.gitContext)
+}
 
-	if err != nil {
-		return nil, err
+// CommentContext returns existing comment like Comment, cancelling GitHub
+// requests when ctx is done or the timeout expires
+func (p Plugin) CommentContext(ctx context.Context) (*github.IssueComment, error) {
+	if p.gitClient == nil {
+		return nil, fmt.Errorf("Comment(): git client not initialized")
 	}
 
-	return filterComment(comments, p.Key), nil
+	parent := ctx
+	ctx, cancel := p.withTimeout(parent)
+	defer cancel()
+
+	p.gitContext = ctx
+	comment, err := p.findComment(ctx)
+
+	return comment, p.maskError(p.timedOut(parent, ctx, apiError(err)))
 }
 
 func (p Plugin) allIssueComments(ctx context.Context) ([]*github.IssueComment, error) {
-	if p.gitClient == nil {
+	if p.gitClient == nil || p.comments == nil {
 		return nil, fmt.Errorf("allIssueComments(): git client not initialized")
 	}
 
-	opts := &github.IssueListCommentsOptions{}
-
-	// get all pages of results
-	var allComments []*github.IssueComment
-	for {
-		comments, resp, err := p.gitClient.Issues.ListComments(ctx, p.RepoOwner, p.RepoName, p.IssueNum, opts)
-		if err != nil {
-			return nil, err
-		}
-		allComments = append(allComments, comments...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
-	}
-
-	return allComments, nil
+	return p.comments.list(ctx, p, p.IssueNum)
 }
 
 func defaultKey(p Plugin) string {
@@ -188,7 +561,7 @@ func defaultKey(p Plugin) string {
 
 func filterComment(comments []*github.IssueComment, key string) *github.IssueComment {
 	for _, comment := range comments {
-		if strings.Contains(*comment.Body, fmt.Sprintf("<!-- id: %s -->", key)) {
+		if md := ParseMetadata(comment.GetBody()); md != nil && md.Key == key {
 			return comment
 		}
 	}
@@ -197,9 +570,133 @@ func filterComment(comments []*github.IssueComment, key string) *github.IssueCom
 }
 
 func (p Plugin) validate() error {
-	if p.Token == "" && (p.Username == "" || p.Password == "") {
+	if p.gitClient == nil && !p.forkSafe() && p.Token == "" && (p.Username == "" || p.Password == "") {
 		return fmt.Errorf("You must provide an API key or Username and Password")
 	}
 
+	switch p.OnEmpty {
+	case "", emptyFail:
+		if p.needsMessage() && !p.resolving() && len(p.Manifest) == 0 && isEmpty(p.Message) && isEmpty(p.Template) {
+			return fmt.Errorf("You must provide a message or message file")
+		}
+	case emptySkip:
+	case emptyDelete:
+		// manifest entries update unless their mode is create
+		if !p.Update && len(p.Manifest) == 0 {
+			return fmt.Errorf("You must enable update to delete empty comments")
+		}
+	default:
+		return fmt.Errorf("Invalid on_empty %q, must be one of %s, %s or %s", p.OnEmpty, emptyFail, emptySkip, emptyDelete)
+	}
+
+	switch p.ManifestPolicy {
+	case "", policyFailFast, policyFailAtEnd, policyNever:
+	default:
+		return fmt.Errorf("Invalid manifest_policy %q, must be one of %s, %s or %s", p.ManifestPolicy, policyFailFast, policyFailAtEnd, policyNever)
+	}
+
+	if err := validateManifest(p.Manifest); err != nil {
+		return err
+	}
+
+	for _, status := range p.OnStatus {
+		switch status {
+		case statusSuccess, statusFailure, statusChanged:
+		default:
+			return fmt.Errorf("Invalid on_status %q, must be one of %s, %s or %s", status, statusSuccess, statusFailure, statusChanged)
+		}
+	}
+
+	// GitHub Actions and Jenkins don't expose the status of the build
+	if len(p.OnStatus) > 0 && p.BuildStatus == "" {
+		return fmt.Errorf("You must provide the build status to use on_status")
+	}
+
+	switch p.Resolve {
+	case "":
+	case resolveUpdate, resolveMinimize:
+		if !p.Update {
+			return fmt.Errorf("You must enable update to resolve comments")
+		}
+
+		if p.BuildStatus == "" {
+			return fmt.Errorf("You must provide the build status to resolve comments")
+		}
+	default:
+		return fmt.Errorf("Invalid resolve %q, must be %s or %s", p.Resolve, resolveUpdate, resolveMinimize)
+	}
+
+	switch p.ForkMode {
+	case "", forkPost, forkSkip:
+	case forkArtifact:
+		if len(p.Manifest) != 0 {
+			return fmt.Errorf("You must not use a manifest with the %s fork_mode", forkArtifact)
+		}
+	default:
+		return fmt.Errorf("Invalid fork_mode %q, must be one of %s, %s or %s", p.ForkMode, forkPost, forkSkip, forkArtifact)
+	}
+
+	switch p.Search {
+	case "", searchOldest, searchNewest:
+	default:
+		return fmt.Errorf("Invalid search %q, must be %s or %s", p.Search, searchOldest, searchNewest)
+	}
+
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		return fmt.Errorf("You must provide both a client certificate and key")
+	}
+
+	if p.Timeout < 0 || p.RequestTimeout < 0 {
+		return fmt.Errorf("Invalid timeout, must not be negative")
+	}
+
+	if p.RequestReviews && !p.Codeowners {
+		return fmt.Errorf("You must enable codeowners to request reviews")
+	}
+
+	switch p.StatusTarget {
+	case "":
+	case statusCommit, statusCheck:
+		if p.CommitSHA == "" {
+			return fmt.Errorf("You must provide the commit SHA to publish a status")
+		}
+
+		if p.BuildStatus == "" {
+			return fmt.Errorf("You must provide the build status to publish a status")
+		}
+	default:
+		return fmt.Errorf("Invalid status %q, must be %s or %s", p.StatusTarget, statusCommit, statusCheck)
+	}
+
+	if p.LabelColor != "" && !labelColorPattern.MatchString(p.LabelColor) {
+		return fmt.Errorf("Invalid label_color %q, must be a 6 character hex code", p.LabelColor)
+	}
+
+	if err := p.validateStates(); err != nil {
+		return err
+	}
+
+	if err := p.validateRedact(); err != nil {
+		return err
+	}
+
+	if err := validateReactions(p.Reactions); err != nil {
+		return err
+	}
+
+	if err := validateReactions(p.ReactionsRemove); err != nil {
+		return err
+	}
+
+	switch p.ReactionTarget {
+	case "", reactOnPR, reactOnComment:
+	case reactOnTrigger:
+		if p.TriggerComment == 0 {
+			return fmt.Errorf("You must provide the trigger comment to react to")
+		}
+	default:
+		return fmt.Errorf("Invalid reaction_target %q, must be one of %s, %s or %s", p.ReactionTarget, reactOnPR, reactOnComment, reactOnTrigger)
+	}
+
 	return nil
 }
@@ -3,6 +3,7 @@ package plugin
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/franela/goblin"
 	"gopkg.in/h2non/gock.v1"
@@ -11,6 +12,10 @@ import (
 func TestPlugin(t *testing.T) {
 	g := goblin.Goblin(t)
 
+	now = func() time.Time {
+		return time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
 	g.Describe("NewFromPlugin", func() {
 		pl := Plugin{
 			BaseURL:   "http://server.com",
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	checkOK      = "ok"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+	checkUnknown = "unknown"
+)
+
+type (
+	preflightCheck struct {
+		Name   string
+		Result string
+		Detail string
+	}
+
+	preflightChecks []preflightCheck
+)
+
+// preflight verifies before any writes that the repository is visible, the
+// issue exists and is not locked unless on_locked allows it, and the identity
+// can comment. Failed checks skip the checks depending on them.
+func (p Plugin) preflight(ctx context.Context) error {
+	var checks preflightChecks
+
+	repo, _, err := p.gitClient.Repositories.Get(ctx, p.RepoOwner, p.RepoName)
+
+	if err != nil {
+		checks = append(checks,
+			preflightCheck{"repository", checkFailed, apiError(err).Error()},
+			preflightCheck{"issue", checkSkipped, ""},
+			preflightCheck{"permission", checkSkipped, ""},
+		)
+		return checks.err()
+	}
+
+	visibility := "public"
+	if repo.GetPrivate() {
+		visibility = "private"
+	}
+	checks = append(checks, preflightCheck{"repository", checkOK, fmt.Sprintf("%s/%s is %s", p.RepoOwner, p.RepoName, visibility)})
+
+	issue, _, err := p.gitClient.Issues.Get(ctx, p.RepoOwner, p.RepoName, p.IssueNum)
+
+	switch {
+	case err != nil:
+		checks = append(checks, preflightCheck{"issue", checkFailed, apiError(err).Error()})
+	case issue.GetLocked() && p.lockedPolicy() == stateFail:
+		checks = append(checks, preflightCheck{"issue", checkFailed, fmt.Sprintf("#%d is locked", p.IssueNum)})
+	case issue.GetLocked():
+		checks = append(checks, preflightCheck{"issue", checkOK, fmt.Sprintf("#%d is locked, on_locked is %s", p.IssueNum, p.lockedPolicy())})
+	default:
+		checks = append(checks, preflightCheck{"issue", checkOK, fmt.Sprintf("#%d is %s", p.IssueNum, issue.GetState())})
+	}
+
+	checks = append(checks, p.permissionCheck(ctx, repo))
+
+	if err := checks.err(); err != nil {
+		return err
+	}
+
+	p.log().Infof("Preflight checks passed\n%s", checks.table())
+	return nil
+}
+
+// permissionCheck checks the identity can comment. Anyone can comment on public
+// repositories, private ones need read access.
+func (p Plugin) permissionCheck(ctx context.Context, repo *github.Repository) preflightCheck {
+	check := preflightCheck{Name: "permission"}
+
+	level, err := p.permissionLevel(ctx, repo)
+
+	switch {
+	case err != nil:
+		check.Result = checkUnknown
+		check.Detail = fmt.Sprintf("could not read permissions. %s", err)
+	case level == "none" && repo.GetPrivate():
+		check.Result = checkFailed
+		check.Detail = "no access, commenting on a private repository needs read access"
+	default:
+		check.Result = checkOK
+		check.Detail = fmt.Sprintf("%s access", level)
+	}
+
+	return check
+}
+
+// permissionLevel returns admin, write, read or none. Repository permissions
+// are missing for some tokens, the collaborator permission is used instead.
+func (p Plugin) permissionLevel(ctx context.Context, repo *github.Repository) (string, error) {
+	if repo.Permissions != nil {
+		perms := *repo.Permissions
+
+		switch {
+		case perms["admin"]:
+			return "admin", nil
+		case perms["push"]:
+			return "write", nil
+		case perms["pull"]:
+			return "read", nil
+		default:
+			return "none", nil
+		}
+	}
+
+	user, _, err := p.gitClient.Users.Get(ctx, "")
+
+	if err != nil {
+		return "", apiError(err)
+	}
+
+	level, _, err := p.gitClient.Repositories.GetPermissionLevel(ctx, p.RepoOwner, p.RepoName, user.GetLogin())
+
+	if err != nil {
+		return "", apiError(err)
+	}
+
+	return level.GetPermission(), nil
+}
+
+// table returns the checks as an aligned table
+func (c preflightChecks) table() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CHECK\tRESULT\tDETAIL")
+	for _, check := range c {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Result, check.Detail)
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+// err returns an error with the table if a check failed
+func (c preflightChecks) err() error {
+	for _, check := range c {
+		if check.Result == checkFailed {
+			return fmt.Errorf("Preflight checks failed\n%s", c.table())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestPreflight(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("preflight", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			Preflight: true,
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		repo := func(private bool, perms map[string]bool) {
+			body := map[string]interface{}{"name": "test-repo", "private": private}
+			if perms != nil {
+				body["permissions"] = perms
+			}
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo$").
+				Reply(200).
+				JSON(body)
+		}
+
+		issue := func(locked bool) {
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12$").
+				Reply(200).
+				JSON(map[string]interface{}{"number": 12, "state": "open", "locked": locked})
+		}
+
+		g.It("passes with access to an open issue", func() {
+			defer gock.Off()
+
+			repo(true, map[string]bool{"pull": true})
+			issue(false)
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("fails on a locked issue", func() {
+			defer gock.Off()
+
+			repo(false, map[string]bool{"pull": true})
+			issue(true)
+
+			pl := pl
+			pl.OnLocked = "fail"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.Contains(err.Error(), "issue       failed  #12 is locked")).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("passes on a locked issue that is skipped", func() {
+			defer gock.Off()
+
+			repo(false, map[string]bool{"pull": true})
+			issue(true)
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("skips later checks when the repository is not visible", func() {
+			defer gock.Off()
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo$").
+				Reply(404).
+				JSON(map[string]interface{}{"message": "Not Found"})
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.HasPrefix(err.Error(), "Preflight checks failed\nCHECK       RESULT   DETAIL\nrepository  failed   GET /api/v3/repos/test-org/test-repo: 404 Not Found")).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(strings.Contains(err.Error(), "issue       skipped")).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("fails without access to a private repository", func() {
+			defer gock.Off()
+
+			repo(true, nil)
+			issue(false)
+
+			gock.New("http://server.com").
+				Get("user$").
+				Reply(200).
+				File("../testdata/response/user.json")
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/collaborators/.*/permission").
+				Reply(200).
+				JSON(map[string]interface{}{"permission": "none"})
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(strings.Contains(err.Error(), "no access, commenting on a private repository needs read access")).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("does not fail when permissions can not be read", func() {
+			defer gock.Off()
+
+			repo(false, nil)
+			issue(false)
+
+			gock.New("http://server.com").
+				Get("user$").
+				Reply(403).
+				JSON(map[string]interface{}{"message": "Resource not accessible by integration"})
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+	})
+}
@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	reactOnPR      = "pr"
+	reactOnComment = "comment"
+	reactOnTrigger = "trigger"
+)
+
+var reactionContents = []string{"+1", "-1", "laugh", "confused", "heart", "hooray", "rocket", "eyes"}
+
+// react adds and removes the configured reactions. comment is the keyed
+// comment written by this run, nil if none was written.
+func (p Plugin) react(comment *github.IssueComment) error {
+	add := p.statusReactions(p.Reactions)
+	remove := p.statusReactions(p.ReactionsRemove)
+
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	issue, id, err := p.reactionTarget(comment)
+
+	if err != nil || (issue == 0 && id == 0) {
+		return err
+	}
+
+	if len(remove) > 0 {
+		if err := p.removeReactions(p.gitContext, issue, id, remove); err != nil {
+			return err
+		}
+	}
+
+	for _, content := range add {
+		if issue != 0 {
+			_, _, err = p.gitClient.Reactions.CreateIssueReaction(p.gitContext, p.RepoOwner, p.RepoName, issue, content)
+		} else {
+			_, _, err = p.gitClient.Reactions.CreateIssueCommentReaction(p.gitContext, p.RepoOwner, p.RepoName, id, content)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reactionTarget returns either the issue number or the comment ID to react to
+func (p Plugin) reactionTarget(comment *github.IssueComment) (int, int64, error) {
+	switch p.ReactionTarget {
+	case reactOnComment:
+		if comment == nil {
+			var err error
+			comment, err = p.findComment(p.gitContext)
+
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+
+		if comment == nil {
+			p.log().Info("Skipped reactions because there is no comment to react to")
+			return 0, 0, nil
+		}
+
+		return 0, comment.GetID(), nil
+	case reactOnTrigger:
+		return 0, p.TriggerComment, nil
+	default:
+		return p.IssueNum, 0, nil
+	}
+}
+
+// removeReactions removes reactions of the authenticated user, ignoring ones not present
+func (p Plugin) removeReactions(ctx context.Context, issue int, id int64, contents []string) error {
+	user, resp, err := p.gitClient.Users.Get(ctx, "")
+
+	// GitHub App installation tokens, like GITHUB_TOKEN of Actions, have no user
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("Failed to remove reactions. Removing reactions needs a user token, GitHub App installation tokens can not look up their user")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%v/%v/issues/comments/%d/reactions", p.RepoOwner, p.RepoName, id)
+	if issue != 0 {
+		path = fmt.Sprintf("repos/%v/%v/issues/%d/reactions", p.RepoOwner, p.RepoName, issue)
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		var (
+			reactions []*github.Reaction
+			resp      *github.Response
+		)
+
+		if issue != 0 {
+			reactions, resp, err = p.gitClient.Reactions.ListIssueReactions(ctx, p.RepoOwner, p.RepoName, issue, opts)
+		} else {
+			reactions, resp, err = p.gitClient.Reactions.ListIssueCommentReactions(ctx, p.RepoOwner, p.RepoName, id, opts)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, r := range reactions {
+			if r.GetUser().GetLogin() != user.GetLogin() || !contains(contents, r.GetContent()) {
+				continue
+			}
+
+			req, err := p.gitClient.NewRequest("DELETE", fmt.Sprintf("%s/%d", path, r.GetID()), nil)
+			if err != nil {
+				return err
+			}
+			if _, err := p.gitClient.Do(ctx, req, nil); err != nil {
+				return err
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// statusReactions returns the reactions that apply to the build status.
+// Reactions may be prefixed with a status, e.g. `failure:confused`.
+func (p Plugin) statusReactions(reactions []string) []string {
+	var contents []string
+
+	for _, r := range reactions {
+		parts := strings.SplitN(r, ":", 2)
+
+		if len(parts) == 1 {
+			contents = append(contents, r)
+		} else if parts[0] == p.BuildStatus {
+			contents = append(contents, parts[1])
+		}
+	}
+
+	return contents
+}
+
+func validateReactions(reactions []string) error {
+	for _, r := range reactions {
+		parts := strings.SplitN(r, ":", 2)
+
+		if !contains(reactionContents, parts[len(parts)-1]) {
+			return fmt.Errorf("Invalid reaction %q, must be one of %s", r, strings.Join(reactionContents, ", "))
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestReactions(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("statusReactions", func() {
+		g.It("filters reactions by build status", func() {
+			p := Plugin{BuildStatus: "failure"}
+
+			g.Assert(p.statusReactions([]string{"eyes", "success:rocket", "failure:confused"})).Equal([]string{"eyes", "confused"})
+		})
+	})
+
+	g.Describe("react", func() {
+		pl := Plugin{
+			BaseURL:     "http://server.com",
+			BuildStatus: "failure",
+			IssueNum:    12,
+			Key:         "123",
+			RepoName:    "test-repo",
+			RepoOwner:   "test-org",
+			Reactions:   []string{"success:rocket", "failure:confused"},
+			SkipComment: true,
+			Token:       "fake",
+		}
+
+		g.It("rejects invalid reactions", func() {
+			pl := pl
+			pl.Reactions = []string{"failure:sad"}
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error for invalid reaction")
+		})
+
+		g.It("reacts to the pr instead of commenting", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/reactions").
+				BodyString("confused").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("reacts to the keyed comment", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.ReactionTarget = "comment"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/comments/7/reactions").
+				BodyString("confused").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("removes only own reactions", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.BuildStatus = "success"
+			pl.ReactionsRemove = []string{"confused"}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("user").
+				Reply(200).
+				File("../testdata/response/user.json")
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/reactions").
+				Reply(200).
+				File("../testdata/response/reactions.json")
+
+			gock.New("http://server.com").
+				Delete("repos/test-org/test-repo/issues/12/reactions/1").
+				Reply(204)
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/reactions").
+				BodyString("rocket").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("explains that removing reactions needs a user token", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.ReactionsRemove = []string{"confused"}
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("user").
+				Reply(403).
+				JSON(map[string]string{"message": "Resource not accessible by integration"})
+
+			err = p.Exec()
+
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal("Failed to remove reactions. Removing reactions needs a user token, GitHub App installation tokens can not look up their user")
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	redactMask = "mask"
+	redactFail = "fail"
+)
+
+var (
+	// defaultRedactEnv are environment variables holding secrets
+	defaultRedactEnv = []string{"DRONE_SECRET_*"}
+
+	// defaultRedactPatterns match well known secret formats
+	defaultRedactPatterns = []string{
+		// AWS access key IDs
+		`\b(AKIA|ASIA)[0-9A-Z]{16}\b`,
+		// GitHub tokens
+		`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+		`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,
+		// JWTs
+		`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	}
+)
+
+// redact masks secrets in a comment body: the credentials of the plugin,
+// values of secret environment variables and matches of the redact patterns.
+// Fails instead if on_redact is fail.
+func (p Plugin) redact(body string) (string, error) {
+	count := 0
+	replace := func(secret string) {
+		if len(secret) < minSecretLength {
+			return
+		}
+		count += strings.Count(body, secret)
+		body = strings.Replace(body, secret, masked, -1)
+	}
+
+	for _, secret := range p.secrets() {
+		replace(secret)
+	}
+
+	for _, secret := range p.secretEnv() {
+		replace(secret)
+	}
+
+	patterns, err := p.redactPatterns()
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, pattern := range patterns {
+		body = pattern.ReplaceAllStringFunc(body, func(string) string {
+			count++
+			return masked
+		})
+	}
+
+	if count == 0 {
+		return body, nil
+	}
+
+	if p.OnRedact == redactFail {
+		return "", fmt.Errorf("Refusing to post a comment containing %d secrets", count)
+	}
+
+	p.log().WithField("redactions", count).Warn("Redacted secrets from the comment")
+	return body, nil
+}
+
+// secretEnv returns the values of environment variables matching redact_env
+func (p Plugin) secretEnv() []string {
+	patterns := p.RedactEnv
+	if len(patterns) == 0 {
+		patterns = defaultRedactEnv
+	}
+
+	var values []string
+	for _, env := range os.Environ() {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, kv[0]); ok {
+				values = append(values, kv[1])
+				break
+			}
+		}
+	}
+
+	return values
+}
+
+func (p Plugin) redactPatterns() ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, expr := range append(append([]string{}, defaultRedactPatterns...), p.RedactPatterns...) {
+		pattern, err := regexp.Compile(expr)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid redact_patterns %q. %s", expr, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+func (p Plugin) validateRedact() error {
+	switch p.OnRedact {
+	case "", redactMask, redactFail:
+	default:
+		return fmt.Errorf("Invalid on_redact %q, must be %s or %s", p.OnRedact, redactMask, redactFail)
+	}
+
+	for _, pattern := range p.RedactEnv {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid redact_env %q. %s", pattern, err)
+		}
+	}
+
+	_, err := p.redactPatterns()
+	return err
+}
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+)
+
+const (
+	resolveUpdate   = "update"
+	resolveMinimize = "minimize"
+
+	defaultResolveMessage = "Resolved in build #{{ .Build }} ({{ .Commit }})"
+
+	minimizeMutation = `mutation($id: ID!) {
+  minimizeComment(input: {subjectId: $id, classifier: RESOLVED}) {
+    minimizedComment { isMinimized }
+  }
+}`
+
+	unminimizeMutation = `mutation($id: ID!) {
+  unminimizeComment(input: {subjectId: $id}) {
+    unminimizedComment { isMinimized }
+  }
+}`
+)
+
+// resolving returns true if this run should resolve the keyed comment instead of posting
+func (p Plugin) resolving() bool {
+	return p.Resolve != "" && p.BuildStatus == statusSuccess
+}
+
+// resolve rewrites or minimizes the keyed comment, doing nothing if none exist
+func (p Plugin) resolve() error {
+	comment, err := p.findComment(p.gitContext)
+
+	if err != nil {
+		return err
+	}
+
+	if comment == nil {
+		p.log().Info("Skipped because there is no comment to resolve")
+		return nil
+	}
+
+	prev := ParseMetadata(comment.GetBody())
+	if prev != nil && prev.Resolved {
+		p.log().Info("Skipped because the comment is already resolved")
+		return nil
+	}
+
+	// Don't let a late finishing build resolve the failure of a newer one
+	if prev != nil && prev.Build > p.BuildNumber && p.BuildNumber != 0 {
+		p.log().WithFields(logrus.Fields{
+			"build":    p.BuildNumber,
+			"previous": prev.Build,
+		}).Info("Skipping resolve, comment was written by a newer build")
+		return nil
+	}
+
+	switch p.Resolve {
+	case resolveMinimize:
+		if err := p.minimizeComment(p.gitContext, comment.GetID(), minimizeMutation); err != nil {
+			return err
+		}
+
+		return p.markMinimized(comment)
+	default:
+		return p.resolveComment(comment, prev)
+	}
+}
+
+// markMinimized stores the resolved state in the marker of a minimized comment
+// so later builds neither minimize it again nor edit it while hidden
+func (p Plugin) markMinimized(comment *github.IssueComment) error {
+	md := p.metadata()
+	md.Resolved = true
+	md.Minimized = true
+
+	body, err := replaceMarker(comment.GetBody(), md)
+
+	if err != nil {
+		return err
+	}
+
+	comment, _, err = p.gitClient.Issues.EditComment(p.gitContext, p.RepoOwner, p.RepoName, int(comment.GetID()), &github.IssueComment{Body: &body})
+
+	if err != nil {
+		return err
+	}
+
+	p.comments.replace(p.IssueNum, comment)
+	return nil
+}
+
+func (p Plugin) resolveComment(comment *github.IssueComment, prev *Metadata) error {
+	rp := p
+	rp.Template = p.ResolveMessage
+	if rp.Template == "" {
+		rp.Template = defaultResolveMessage
+	}
+
+	message, err := rp.render(prev, nil)
+
+	if err != nil {
+		return err
+	}
+
+	message, err = p.redact(message)
+
+	if err != nil {
+		return err
+	}
+
+	md := p.metadata()
+	md.Resolved = true
+	marker, err := md.Marker()
+
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("%s\n%s\n", message, marker)
+	comment, _, err = p.gitClient.Issues.EditComment(p.gitContext, p.RepoOwner, p.RepoName, int(comment.GetID()), &github.IssueComment{Body: &body})
+
+	if err != nil {
+		return err
+	}
+
+	p.comments.replace(p.IssueNum, comment)
+	return nil
+}
+
+// minimizeComment runs the minimize or unminimize mutation on a comment
+func (p Plugin) minimizeComment(ctx context.Context, id int64, mutation string) error {
+	req, err := p.gitClient.NewRequest("GET", fmt.Sprintf("repos/%v/%v/issues/comments/%d", p.RepoOwner, p.RepoName, id), nil)
+
+	if err != nil {
+		return err
+	}
+
+	// go-github does not expose the node ID of comments
+	comment := &struct {
+		NodeID string `json:"node_id"`
+	}{}
+	if _, err := p.gitClient.Do(ctx, req, comment); err != nil {
+		return err
+	}
+
+	return p.graphql(ctx, mutation, map[string]interface{}{"id": comment.NodeID}, nil)
+}
@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/google/go-github/github"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestResolve(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("resolve comment", func() {
+		pl := Plugin{
+			BaseURL:     "http://server.com",
+			BuildNumber: 12,
+			BuildStatus: "success",
+			CommitSHA:   "abc123",
+			Message:     "test message",
+			IssueNum:    12,
+			Key:         "123",
+			RepoName:    "test-repo",
+			RepoOwner:   "test-org",
+			Resolve:     "update",
+			Update:      true,
+			Token:       "fake",
+		}
+
+		g.It("requires update", func() {
+			pl := pl
+			pl.Update = false
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that update is required")
+		})
+
+		g.It("requires the build status", func() {
+			pl := pl
+			pl.BuildStatus = ""
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that the build status is required")
+		})
+
+		g.It("does nothing if no comment exists", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/non-existing-comment.json")
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("rewrites the existing comment", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				BodyString(`Resolved in build #12 \(abc123\)`).
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("does not post on failure", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.BuildStatus = "failure"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				BodyString("test message").
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("minimizes the existing comment", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Resolve = "minimize"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				File("../testdata/response/existing-comment.json")
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/comments/7").
+				Reply(200).
+				File("../testdata/response/comment.json")
+
+			gock.New("http://server.com").
+				Post("graphql").
+				BodyString("MDEyOklzc3VlQ29tbWVudDc=").
+				Reply(200).
+				JSON(map[string]interface{}{"data": map[string]interface{}{}})
+
+			var edit github.IssueComment
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				Map(func(req *http.Request) *http.Request {
+					data, _ := ioutil.ReadAll(req.Body)
+					req.Body = ioutil.NopCloser(bytes.NewReader(data))
+					json.Unmarshal(data, &edit)
+					return req
+				}).
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+
+			md := ParseMetadata(edit.GetBody())
+			g.Assert(md != nil).IsTrue(edit.GetBody())
+			g.Assert(md.Key).Equal("123")
+			g.Assert(md.Build).Equal(12)
+			g.Assert(md.Resolved).IsTrue()
+			g.Assert(md.Minimized).IsTrue()
+		})
+
+		// comments returns a comment list with a single keyed comment
+		comments := func(md Metadata) []map[string]interface{} {
+			marker, _ := md.Marker()
+			return []map[string]interface{}{{"id": 7, "body": "test message\n" + marker + "\n"}}
+		}
+
+		g.It("does not resolve the comment of a newer build", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				JSON(comments(Metadata{Key: "123", Build: 13}))
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("unminimizes the comment on the next failure", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.BuildNumber = 14
+			pl.BuildStatus = "failure"
+			pl.Resolve = "minimize"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				Reply(200).
+				JSON(comments(Metadata{Key: "123", Build: 13, Resolved: true, Minimized: true}))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/comments/7").
+				Reply(200).
+				File("../testdata/response/comment.json")
+
+			gock.New("http://server.com").
+				Post("graphql").
+				BodyString("unminimizeComment").
+				Reply(200).
+				JSON(map[string]interface{}{"data": map[string]interface{}{}})
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/issues/comments/7").
+				BodyString("test message").
+				Reply(200).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	searchOldest = "oldest"
+	searchNewest = "newest"
+)
+
+// parseSince parses a since setting, either a RFC 3339 timestamp or a duration
+// before now. Zero time if value is empty.
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(value)
+
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("Invalid since %q, must be a RFC 3339 timestamp or a positive duration", value)
+	}
+
+	return now().Add(-d), nil
+}
+
+// findComment returns the comment with the key of the plugin, nil if none
+// exist. The oldest search returns the first matching comment of the full
+// listing, the newest search scans from the last page and stops at the first
+// match.
+func (p Plugin) findComment(ctx context.Context) (*github.IssueComment, error) {
+	if p.Search != searchNewest {
+		comments, err := p.allIssueComments(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return filterComment(comments, p.Key), nil
+	}
+
+	if p.gitClient == nil || p.comments == nil {
+		return nil, fmt.Errorf("findComment(): git client not initialized")
+	}
+
+	return p.comments.findNewest(ctx, p, p.IssueNum, p.Key)
+}
+
+// findNewest returns the newest comment of an issue with key. Page 1 is always
+// fetched to learn the number of pages, later pages are revalidated with their
+// ETag. A fresh listing is searched without requests.
+func (idx *commentIndex) findNewest(ctx context.Context, p Plugin, issue int, key string) (*github.IssueComment, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.fresh[issue] {
+		pages := idx.issues[issue]
+		for n := len(pages) - 1; n >= 0; n-- {
+			if comment := lastMatch(pages[n].comments, key); comment != nil {
+				return comment, nil
+			}
+		}
+		return nil, nil
+	}
+
+	// page 1 is unchanged when comments are added, so its cached copy can not
+	// tell the number of pages
+	first, err := p.fetchCommentPage(ctx, issue, 1, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	count := first.last
+	if count < 1 {
+		count = 1
+	}
+
+	pages := make([]*commentPage, count)
+	copy(pages, idx.issues[issue])
+	pages[0] = first
+	idx.issues[issue] = pages
+
+	for number := count; number > 1; number-- {
+		page, err := p.fetchCommentPage(ctx, issue, number, pages[number-1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		pages[number-1] = page
+		if comment := lastMatch(page.comments, key); comment != nil {
+			return comment, nil
+		}
+	}
+
+	return lastMatch(first.comments, key), nil
+}
+
+// lastMatch returns the last comment with key
+func lastMatch(comments []*github.IssueComment, key string) *github.IssueComment {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if md := ParseMetadata(comments[i].GetBody()); md != nil && md.Key == key {
+			return comments[i]
+		}
+	}
+
+	return nil
+}
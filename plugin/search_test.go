@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSearch(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("newest first search", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Search:    searchNewest,
+			Update:    true,
+			Token:     "fake",
+		}
+
+		g.It("stops at the newest page with a match", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "^1$").
+				Reply(200).
+				SetHeader("Link", `<http://server.com/repos/test-org/test-repo/issues/12/comments?page=2>; rel="next", <http://server.com/repos/test-org/test-repo/issues/12/comments?page=3>; rel="last"`).
+				JSON([]map[string]interface{}{{"id": 1, "body": "old\n<!-- id: 123 -->\n"}})
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "^3$").
+				Reply(200).
+				JSON([]map[string]interface{}{
+					{"id": 5, "body": "new\n<!-- id: 123 -->\n"},
+					{"id": 6, "body": "other\n<!-- id: 456 -->\n"},
+				})
+
+			comment, err := p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(comment.GetID()).Equal(int64(5))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("falls back to the first page", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "^1$").
+				Reply(200).
+				SetHeader("Link", `<http://server.com/repos/test-org/test-repo/issues/12/comments?page=2>; rel="next", <http://server.com/repos/test-org/test-repo/issues/12/comments?page=2>; rel="last"`).
+				JSON([]map[string]interface{}{{"id": 1, "body": "old\n<!-- id: 123 -->\n"}})
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("page", "^2$").
+				Reply(200).
+				JSON([]map[string]interface{}{{"id": 6, "body": "other\n<!-- id: 456 -->\n"}})
+
+			comment, err := p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(comment.GetID()).Equal(int64(1))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("filters comments with since", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Since = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12/comments").
+				MatchParam("since", "^2018-01-01T00:00:00Z$").
+				Reply(200).
+				JSON([]map[string]interface{}{})
+
+			comment, err := p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(comment == nil).IsTrue()
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("rejects an invalid search", func() {
+			pl := pl
+			pl.Search = "random"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+
+	g.Describe("since", func() {
+		g.It("parses a timestamp", func() {
+			since, err := parseSince("2018-01-01T00:00:00Z")
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(since.Equal(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))).IsTrue()
+		})
+
+		g.It("parses a duration before now", func() {
+			since, err := parseSince("24h")
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(since.Equal(now().Add(-24 * time.Hour))).IsTrue()
+		})
+
+		g.It("rejects other values", func() {
+			_, err := parseSince("yesterday")
+			g.Assert(err != nil).IsTrue("Expected an error")
+
+			_, err = parseSince("-1h")
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+}
+
+// commentServer serves an issue with count comments, the keyed comment being
+// near the newest
+func commentServer(count int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		last := (count + perPage - 1) / perPage
+
+		link := func(n int, rel string) string {
+			return fmt.Sprintf(`<http://%s%s?per_page=%d&page=%d>; rel="%s"`, r.Host, r.URL.Path, perPage, n, rel)
+		}
+		if page < last {
+			w.Header().Set("Link", link(page+1, "next")+", "+link(last, "last"))
+		}
+
+		var comments []map[string]interface{}
+		for id := (page-1)*perPage + 1; id <= page*perPage && id <= count; id++ {
+			body := fmt.Sprintf("comment %d", id)
+			if id == count-10 {
+				body += "\n<!-- id: 123 -->\n"
+			}
+			comments = append(comments, map[string]interface{}{"id": id, "body": body})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comments)
+	}))
+}
+
+func benchmarkSearch(b *testing.B, search string) {
+	server := commentServer(5000)
+	defer server.Close()
+
+	p, err := NewFromPlugin(Plugin{
+		BaseURL:   server.URL,
+		IssueNum:  12,
+		Key:       "123",
+		Message:   "test message",
+		RepoName:  "test-repo",
+		RepoOwner: "test-org",
+		Search:    search,
+		Token:     "fake",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.comments.invalidate()
+
+		comment, err := p.Comment()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if comment.GetID() != 4990 {
+			b.Fatalf("Found comment %d", comment.GetID())
+		}
+	}
+}
+
+func BenchmarkSearchOldest(b *testing.B) {
+	benchmarkSearch(b, searchOldest)
+}
+
+func BenchmarkSearchNewest(b *testing.B) {
+	benchmarkSearch(b, searchNewest)
+}
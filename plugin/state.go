@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	statePost = "post"
+	stateSkip = "skip"
+	stateFail = "fail"
+)
+
+// needsState reports if the state of the PR/issue must be fetched before
+// posting. Locked conversations are skipped by default when posting fails.
+func (p Plugin) needsState() bool {
+	return (p.OnClosed != "" && p.OnClosed != statePost) ||
+		(p.OnMerged != "" && p.OnMerged != statePost) ||
+		p.OnLocked == stateFail
+}
+
+// stateReason returns why to skip posting because of the state of the PR/issue,
+// or an error if the policy for the state is to fail
+func (p Plugin) stateReason(ctx context.Context) (string, error) {
+	if !p.needsState() {
+		return "", nil
+	}
+
+	issue, _, err := p.gitClient.Issues.Get(ctx, p.RepoOwner, p.RepoName, p.IssueNum)
+
+	if err != nil {
+		return "", err
+	}
+
+	if issue.GetLocked() {
+		if reason, err := p.applyState(p.lockedPolicy(), "locked"); reason != "" || err != nil {
+			return reason, err
+		}
+	}
+
+	if issue.GetState() != "closed" {
+		return "", nil
+	}
+
+	if issue.PullRequestLinks != nil {
+		pr, _, err := p.gitClient.PullRequests.Get(ctx, p.RepoOwner, p.RepoName, p.IssueNum)
+
+		if err != nil {
+			return "", err
+		}
+
+		if pr.GetMerged() {
+			return p.applyState(p.OnMerged, "merged")
+		}
+	}
+
+	return p.applyState(p.OnClosed, "closed")
+}
+
+func (p Plugin) applyState(policy, state string) (string, error) {
+	switch policy {
+	case stateSkip:
+		return fmt.Sprintf("#%d is %s", p.IssueNum, state), nil
+	case stateFail:
+		return "", fmt.Errorf("Refusing to comment, #%d is %s", p.IssueNum, state)
+	default:
+		return "", nil
+	}
+}
+
+func (p Plugin) lockedPolicy() string {
+	if p.OnLocked == "" {
+		return stateSkip
+	}
+
+	return p.OnLocked
+}
+
+// isLocked reports if err is GitHub refusing a comment on a locked conversation
+func isLocked(err error) bool {
+	e, ok := err.(*github.ErrorResponse)
+
+	return ok && e.Response != nil && e.Response.StatusCode == http.StatusUnprocessableEntity && strings.Contains(errorDetails(e), "locked")
+}
+
+// validateStates checks the state policies in order so errors are deterministic
+func (p Plugin) validateStates() error {
+	for _, policy := range []struct{ name, value string }{
+		{"on_closed", p.OnClosed},
+		{"on_merged", p.OnMerged},
+		{"on_locked", p.OnLocked},
+	} {
+		switch policy.value {
+		case "", statePost, stateSkip, stateFail:
+		default:
+			return fmt.Errorf("Invalid %s %q, must be one of %s, %s or %s", policy.name, policy.value, statePost, stateSkip, stateFail)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestState(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("state policy", func() {
+		pl := Plugin{
+			BaseURL:   "http://server.com",
+			Message:   "test message",
+			IssueNum:  12,
+			Key:       "123",
+			RepoName:  "test-repo",
+			RepoOwner: "test-org",
+			Token:     "fake",
+		}
+
+		issue := func(state string, locked bool) {
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/issues/12$").
+				Reply(200).
+				JSON(map[string]interface{}{
+					"number":       12,
+					"state":        state,
+					"locked":       locked,
+					"pull_request": map[string]interface{}{"url": "http://server.com/repos/test-org/test-repo/pulls/12"},
+				})
+		}
+
+		g.It("skips merged PRs", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnMerged = "skip"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			issue("closed", false)
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/pulls/12$").
+				Reply(200).
+				JSON(map[string]interface{}{"number": 12, "merged": true})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("applies the closed policy to closed unmerged PRs", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnMerged = "skip"
+			pl.OnClosed = "fail"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			issue("closed", false)
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/pulls/12$").
+				Reply(200).
+				JSON(map[string]interface{}{"number": 12, "merged": false})
+
+			err = p.Exec()
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal("Refusing to comment, #12 is closed")
+		})
+
+		g.It("applies the merged policy to merged PRs", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnClosed = "fail"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			issue("closed", false)
+
+			gock.New("http://server.com").
+				Get("repos/test-org/test-repo/pulls/12$").
+				Reply(200).
+				JSON(map[string]interface{}{"number": 12, "merged": true})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("posts on open PRs", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnClosed = "skip"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			issue("open", false)
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]interface{}{"id": 7})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("fails on locked conversations before posting", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.OnLocked = "fail"
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			issue("open", true)
+
+			err = p.Exec()
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal("Refusing to comment, #12 is locked")
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("skips locked conversations by default without fetching the state", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(422).
+				JSON(map[string]interface{}{
+					"message": "Validation Failed",
+					"errors":  []map[string]interface{}{{"resource": "IssueComment", "code": "unprocessable", "message": "Unable to create comment because issue is locked."}},
+				})
+
+			err = p.Exec()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("rejects an invalid policy", func() {
+			pl := pl
+			pl.OnMerged = "random"
+			pl.OnClosed = "other"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+			g.Assert(err.Error()).Equal(`Invalid on_closed "other", must be one of post, skip or fail`)
+		})
+	})
+}
@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	statusCommit = "commit"
+	statusCheck  = "check"
+
+	defaultStatusContext = "github-comment"
+
+	// limits imposed by the GitHub API
+	maxStatusDescription = 140
+	maxCheckSummary      = 65535
+	maxCheckAnnotations  = 50
+)
+
+type (
+	// Annotation is a check run annotation on a file
+	Annotation struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Level     string `json:"annotation_level"`
+		Title     string `json:"title,omitempty"`
+		Message   string `json:"message"`
+	}
+
+	checkRunOutput struct {
+		Title       string       `json:"title"`
+		Summary     string       `json:"summary"`
+		Annotations []Annotation `json:"annotations,omitempty"`
+	}
+
+	checkRun struct {
+		ID         int64           `json:"id,omitempty"`
+		Name       string          `json:"name,omitempty"`
+		HeadSHA    string          `json:"head_sha,omitempty"`
+		Status     string          `json:"status,omitempty"`
+		Conclusion string          `json:"conclusion,omitempty"`
+		DetailsURL string          `json:"details_url,omitempty"`
+		Output     *checkRunOutput `json:"output,omitempty"`
+	}
+)
+
+// readAnnotations reads a JSON list of annotations, nil if path is empty
+func readAnnotations(path string) ([]Annotation, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read annotations file %s. %s", path, err)
+	}
+
+	var annotations []Annotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("Failed to parse annotations file %s. %s", path, err)
+	}
+
+	return annotations, nil
+}
+
+// publishStatus publishes the message as a commit status or check run
+func (p Plugin) publishStatus(ctx context.Context, message string) error {
+	name := p.StatusContext
+	if name == "" {
+		name = defaultStatusContext
+	}
+
+	title := p.StatusTitle
+	if title == "" {
+		title = strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	}
+
+	if p.StatusTarget == statusCheck {
+		return p.createCheckRun(ctx, name, title, message)
+	}
+
+	status := &github.RepoStatus{
+		State:       github.String(p.commitState()),
+		Context:     github.String(name),
+		Description: github.String(truncate(title, maxStatusDescription)),
+	}
+
+	if p.BuildLink != "" {
+		status.TargetURL = github.String(p.BuildLink)
+	}
+
+	_, _, err := p.gitClient.Repositories.CreateStatus(ctx, p.RepoOwner, p.RepoName, p.CommitSHA, status)
+	return err
+}
+
+// createCheckRun creates a completed check run, adding annotations in batches
+// as the API accepts a limited number per request
+func (p Plugin) createCheckRun(ctx context.Context, name, title, summary string) error {
+	annotations := p.Annotations
+	batch := func() []Annotation {
+		n := len(annotations)
+		if n > maxCheckAnnotations {
+			n = maxCheckAnnotations
+		}
+		b := annotations[:n]
+		annotations = annotations[n:]
+		return b
+	}
+
+	run := &checkRun{
+		Name:       name,
+		HeadSHA:    p.CommitSHA,
+		Status:     "completed",
+		Conclusion: p.checkConclusion(),
+		DetailsURL: p.BuildLink,
+		Output: &checkRunOutput{
+			Title:       title,
+			Summary:     truncate(summary, maxCheckSummary),
+			Annotations: batch(),
+		},
+	}
+
+	if err := p.checkRequest(ctx, "POST", fmt.Sprintf("repos/%v/%v/check-runs", p.RepoOwner, p.RepoName), run); err != nil {
+		return err
+	}
+
+	for len(annotations) > 0 {
+		update := &checkRun{
+			Output: &checkRunOutput{
+				Title:       title,
+				Summary:     truncate(summary, maxCheckSummary),
+				Annotations: batch(),
+			},
+		}
+
+		if err := p.checkRequest(ctx, "PATCH", fmt.Sprintf("repos/%v/%v/check-runs/%d", p.RepoOwner, p.RepoName, run.ID), update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRequest sends a Checks API request, decoding the response into run.
+// The vendored go-github does not support the Checks API.
+func (p Plugin) checkRequest(ctx context.Context, method, path string, run *checkRun) error {
+	req, err := p.gitClient.NewRequest(method, path, run)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.antiope-preview+json")
+
+	result := &checkRun{}
+	if _, err := p.gitClient.Do(ctx, req, result); err != nil {
+		return err
+	}
+
+	if result.ID != 0 {
+		run.ID = result.ID
+	}
+
+	return nil
+}
+
+// commitState maps the build status to a commit status state. Unknown
+// statuses are errors so a failed build is never reported as successful.
+func (p Plugin) commitState() string {
+	switch p.BuildStatus {
+	case statusSuccess:
+		return "success"
+	case statusFailure:
+		return "failure"
+	default:
+		return "error"
+	}
+}
+
+// checkConclusion maps the build status to a check run conclusion. Unknown
+// statuses are failures so a failed build is never reported as successful.
+func (p Plugin) checkConclusion() string {
+	switch p.BuildStatus {
+	case statusSuccess:
+		return "success"
+	case "killed", "cancelled", "canceled":
+		return "cancelled"
+	default:
+		return "failure"
+	}
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/franela/goblin"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestStatus(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("publish status", func() {
+		pl := Plugin{
+			BaseURL:      "http://server.com",
+			BuildLink:    "http://drone.server.com/test-org/test-repo/12",
+			BuildStatus:  "failure",
+			CommitSHA:    "abc123",
+			Message:      "2 tests failed\n\n* TestOne\n* TestTwo",
+			IssueNum:     12,
+			RepoName:     "test-repo",
+			RepoOwner:    "test-org",
+			SkipComment:  true,
+			StatusTarget: "commit",
+			Token:        "fake",
+		}
+
+		g.It("requires a commit sha", func() {
+			pl := pl
+			pl.CommitSHA = ""
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that commit sha is required")
+		})
+
+		g.It("requires a build status", func() {
+			pl := pl
+			pl.BuildStatus = ""
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("should have received error that the build status is required")
+		})
+
+		g.It("never maps an unknown build status to success", func() {
+			for _, status := range []string{"cancelled", "canceled", "killed", "running", "error"} {
+				p := Plugin{BuildStatus: status}
+
+				g.Assert(p.commitState()).Equal("error")
+				g.Assert(p.checkConclusion() != "success").IsTrue(status)
+			}
+
+			g.Assert(Plugin{BuildStatus: "canceled"}.checkConclusion()).Equal("cancelled")
+			g.Assert(Plugin{BuildStatus: "success"}.commitState()).Equal("success")
+		})
+
+		g.It("publishes a commit status", func() {
+			defer gock.Off()
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/statuses/abc123").
+				MatchType("json").
+				JSON(map[string]string{
+					"state":       "failure",
+					"target_url":  "http://drone.server.com/test-org/test-repo/12",
+					"description": "2 tests failed",
+					"context":     "github-comment",
+				}).
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("redacts secrets from the status", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.Token = "supersecrettoken"
+			pl.Message = "login with supersecrettoken failed\n\nsupersecrettoken"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/statuses/abc123").
+				MatchType("json").
+				JSON(map[string]string{
+					"state":       "failure",
+					"target_url":  "http://drone.server.com/test-org/test-repo/12",
+					"description": "login with **** failed",
+					"context":     "github-comment",
+				}).
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+			g.Assert(gock.HasUnmatchedRequest()).IsFalse(fmt.Sprintf("Received unmatched requests: %v\n", gock.GetUnmatchedRequests()))
+		})
+
+		g.It("publishes a check run with annotations in batches", func() {
+			defer gock.Off()
+
+			annotations, err := readAnnotations("../testdata/request/annotations.json")
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			pl := pl
+			pl.Annotations = annotations
+			pl.StatusContext = "lint"
+			pl.StatusTarget = "check"
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/check-runs").
+				BodyString(`"name":"lint".*"conclusion":"failure".*"title":"2 tests failed".*lint error 50"`).
+				Reply(201).
+				JSON(map[string]interface{}{"id": 4})
+
+			gock.New("http://server.com").
+				Patch("repos/test-org/test-repo/check-runs/4").
+				BodyString(`lint error 51.*lint error 52"`).
+				Reply(200).
+				JSON(map[string]interface{}{"id": 4})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+
+		g.It("posts the comment as well", func() {
+			defer gock.Off()
+
+			pl := pl
+			pl.SkipComment = false
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/issues/12/comments").
+				Reply(201).
+				JSON(map[string]string{})
+
+			gock.New("http://server.com").
+				Post("repos/test-org/test-repo/statuses/abc123").
+				Reply(201).
+				JSON(map[string]string{})
+
+			err = p.Exec()
+
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(gock.IsDone()).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+type (
+	// TemplateData is the data available when rendering a message template
+	TemplateData struct {
+		Message  string
+		Key      string
+		Build    int
+		Commit   string
+		Fields   map[string]string
+		Owners   []string
+		Previous *Metadata
+	}
+)
+
+// render returns the message to post, rendering the template if one is set.
+// own is nil unless code owners are enabled.
+func (p Plugin) render(prev *Metadata, own *ownership) (string, error) {
+	if p.Template == "" {
+		return p.Message, nil
+	}
+
+	funcs := template.FuncMap{
+		"owners": func(file string) string {
+			return own.mention(own.owners(file))
+		},
+		"mentions": own.mention,
+	}
+
+	tmpl, err := template.New("message").Funcs(funcs).Parse(p.Template)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse template. %s", err)
+	}
+
+	data := TemplateData{
+		Message:  p.Message,
+		Key:      p.Key,
+		Build:    p.BuildNumber,
+		Commit:   p.CommitSHA,
+		Fields:   p.Metadata,
+		Owners:   own.capped(own.all()),
+		Previous: prev,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("Failed to render template. %s", err)
+	}
+
+	return buf.String(), nil
+}
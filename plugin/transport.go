@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// customTransport reports if TLS or proxy settings require a dedicated transport
+func (p Plugin) customTransport() bool {
+	return p.CACert != "" || p.ClientCert != "" || p.ClientKey != "" || p.SkipVerify || p.Proxy != ""
+}
+
+// transport returns a transport with the TLS and proxy settings of the plugin.
+// Defaults match http.DefaultTransport.
+func (p Plugin) transport() (*http.Transport, error) {
+	tlsConfig, err := p.tlsConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := p.proxyFunc()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}, nil
+}
+
+func (p Plugin) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if p.CACert != "" {
+		pem, err := ioutil.ReadFile(p.CACert)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA bundle %s. %s", p.CACert, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Failed to parse CA bundle %s, no PEM certificates found", p.CACert)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if p.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(p.ClientCert, p.ClientKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate %s. %s", p.ClientCert, err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if p.SkipVerify {
+		p.log().Warn("TLS certificate verification is DISABLED, requests to GitHub can be intercepted. Use ca_cert to trust a private CA instead.")
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// proxyFunc returns the proxy setting honoring NO_PROXY, or the proxy of the
+// environment if unset
+func (p Plugin) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if p.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxy, err := url.Parse(p.Proxy)
+
+	if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy %q, must be a URL like http://proxy:3128", p.Proxy)
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}, nil
+}
+
+// bypassProxy reports if u matches a NO_PROXY entry: *, a host or domain
+// (with or without leading dot, matching subdomains), an IP or a CIDR, each
+// optionally with a port
+func bypassProxy(u *url.URL, noProxy string) bool {
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+
+		if ip != nil {
+			if entryIP := net.ParseIP(entry); entryIP != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(entry, ".")
+		host = strings.ToLower(host)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
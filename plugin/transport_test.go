@@ -0,0 +1,266 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+)
+
+// writeClientCert writes a self signed client certificate and key to dir
+func writeClientCert(dir string) (*x509.Certificate, string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "drone"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		return nil, "", "", err
+	}
+
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, "", "", err
+	}
+
+	return cert, certFile, keyFile, nil
+}
+
+func TestTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	emptyList := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	})
+
+	pl := Plugin{
+		Message:   "test message",
+		IssueNum:  12,
+		Key:       "123",
+		RepoName:  "test-repo",
+		RepoOwner: "test-org",
+		Update:    true,
+		Token:     "fake",
+	}
+
+	g.Describe("tls", func() {
+		var dir string
+
+		g.Before(func() {
+			dir, _ = ioutil.TempDir("", "github-comment")
+		})
+
+		g.After(func() {
+			os.RemoveAll(dir)
+		})
+
+		g.It("trusts a CA bundle", func() {
+			server := httptest.NewTLSServer(emptyList)
+			defer server.Close()
+
+			pl := pl
+			pl.BaseURL = server.URL
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.Comment()
+			g.Assert(err != nil).IsTrue("Expected an unknown authority error")
+
+			ca := filepath.Join(dir, "ca.pem")
+			ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]}), 0600)
+			pl.CACert = ca
+
+			p, err = NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("skips verification", func() {
+			server := httptest.NewTLSServer(emptyList)
+			defer server.Close()
+
+			pl := pl
+			pl.BaseURL = server.URL
+			pl.SkipVerify = true
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("presents a client certificate", func() {
+			cert, certFile, keyFile, err := writeClientCert(dir)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			pool := x509.NewCertPool()
+			pool.AddCert(cert)
+
+			server := httptest.NewUnstartedServer(emptyList)
+			server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+			server.StartTLS()
+			defer server.Close()
+
+			pl := pl
+			pl.BaseURL = server.URL
+			pl.SkipVerify = true
+			pl.ClientCert = certFile
+			pl.ClientKey = keyFile
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+		})
+
+		g.It("requires both client certificate and key", func() {
+			pl := pl
+			pl.BaseURL = "https://server.com"
+			pl.ClientCert = "client.pem"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("fails on a missing CA bundle", func() {
+			pl := pl
+			pl.BaseURL = "https://server.com"
+			pl.CACert = filepath.Join(dir, "missing.pem")
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("can not be combined with a custom transport", func() {
+			pl := pl
+			pl.BaseURL = "https://server.com"
+			pl.SkipVerify = true
+
+			_, err := NewFromPlugin(pl, WithTransport(http.DefaultTransport))
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+	})
+
+	g.Describe("proxy", func() {
+		var requests int
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			emptyList(w, r)
+		}))
+
+		g.After(func() {
+			proxy.Close()
+		})
+
+		pl := pl
+		pl.BaseURL = "http://github.example.com/"
+		pl.Proxy = proxy.URL
+		pl.RequestTimeout = time.Second
+
+		g.It("sends requests through the proxy", func() {
+			requests = 0
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			_, err = p.Comment()
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+			g.Assert(requests).Equal(1)
+		})
+
+		g.It("bypasses the proxy for NO_PROXY hosts", func() {
+			requests = 0
+
+			os.Setenv("NO_PROXY", ".example.com")
+			defer os.Unsetenv("NO_PROXY")
+
+			p, err := NewFromPlugin(pl)
+			g.Assert(err == nil).IsTrue(fmt.Sprintf("Received err: %s", err))
+
+			p.Comment()
+			g.Assert(requests).Equal(0)
+		})
+
+		g.It("rejects an invalid proxy", func() {
+			pl := pl
+			pl.Proxy = "proxy:3128"
+
+			_, err := NewFromPlugin(pl)
+			g.Assert(err != nil).IsTrue("Expected an error")
+		})
+
+		g.It("matches NO_PROXY entries", func() {
+			cases := []struct {
+				url     string
+				noProxy string
+				bypass  bool
+			}{
+				{"https://github.example.com/", "", false},
+				{"https://github.example.com/", "*", true},
+				{"https://github.example.com/", "example.com", true},
+				{"https://github.example.com/", ".example.com", true},
+				{"https://github.example.com/", "other.com, github.example.com", true},
+				{"https://github.example.com/", "notexample.com", false},
+				{"https://github.example.com/", "github.example.com:443", true},
+				{"https://github.example.com/", "github.example.com:8443", false},
+				{"https://10.1.2.3/", "10.0.0.0/8", true},
+				{"https://10.1.2.3/", "10.1.2.3", true},
+				{"https://10.1.2.3/", "192.168.0.0/16", false},
+			}
+
+			for _, c := range cases {
+				u, _ := url.Parse(c.url)
+				g.Assert(bypassProxy(u, c.noProxy)).Equal(c.bypass)
+			}
+		})
+	})
+}